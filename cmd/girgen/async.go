@@ -0,0 +1,203 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/electricface/go-gir3/cmd/girgen/xmlp"
+	"github.com/electricface/go-gir3/gi"
+)
+
+// getAsyncReadyCallback 如果类型 ti 是 Gio.AsyncReadyCallback，则返回它的 CallableInfo，否则返回 nil。
+func getAsyncReadyCallback(ti *gi.TypeInfo) *gi.CallableInfo {
+	if ti.Tag() != gi.TYPE_TAG_INTERFACE {
+		return nil
+	}
+	bi := ti.Interface()
+	if bi.Type() == gi.INFO_TYPE_CALLBACK && bi.Namespace() == "Gio" && bi.Name() == "AsyncReadyCallback" {
+		return gi.ToCallableInfo(bi)
+	}
+	bi.Unref()
+	return nil
+}
+
+// getFinishFuncName 获取异步函数 ctx 对应的 finish 函数的名字，优先使用 glib:finish-func 属性，
+// 否则按照命名惯例，把 xxx_async 或 xxx 对应到 xxx_finish。
+func getFinishFuncName(ctx *pFuncContext, xFuncs []*xmlp.FunctionInfo) string {
+	for _, xFunc := range xFuncs {
+		if xFunc.NameAttr == ctx.name && xFunc.FinishFunc != "" {
+			return xFunc.FinishFunc
+		}
+	}
+	return strings.TrimSuffix(ctx.name, "_async") + "_finish"
+}
+
+// pAsyncWrappers 为 fnCtxs 中的异步函数和 finish 函数对生成同步的包装函数。
+// 比如为 File.LoadContentsAsync 和 File.LoadContentsFinish 生成 File.LoadContentsAwait，
+// 它发起异步操作，等待操作完成后返回 finish 函数的结果。xFuncs 用于获取 glib:finish-func 属性。
+// 返回的 wrappers 只包含生成的包装函数的名称、接收者、参数和返回值信息。
+func pAsyncWrappers(s *SourceFile, fnCtxs []*pFuncContext, xFuncs []*xmlp.FunctionInfo) (wrappers []*pFuncContext) {
+	ctxMap := make(map[string]*pFuncContext, len(fnCtxs))
+	fnNameMap := make(map[string]struct{}, len(fnCtxs))
+	for _, ctx := range fnCtxs {
+		ctxMap[ctx.name] = ctx
+		fnNameMap[ctx.fnName] = struct{}{}
+	}
+
+	for _, ctx := range fnCtxs {
+		if ctx.asyncCbParam == "" || ctx.isOmitted() {
+			continue
+		}
+		finishCtx := ctxMap[getFinishFuncName(ctx, xFuncs)]
+		if finishCtx == nil || finishCtx.isOmitted() {
+			continue
+		}
+		// 接收者要么都有，要么都没有
+		if (ctx.receiver == "") != (finishCtx.receiver == "") {
+			continue
+		}
+
+		fnName := strings.TrimSuffix(ctx.fnName, "Async") + "Await"
+		if _, ok := fnNameMap[fnName]; ok {
+			s.GoBody.Pn("// ignore async wrapper %v, name conflicts\n", fnName)
+			continue
+		}
+		wrapper := pAsyncWrapper(s, fnName, ctx, finishCtx)
+		if wrapper != nil {
+			wrappers = append(wrappers, wrapper)
+		}
+	}
+	return
+}
+
+func pAsyncWrapper(s *SourceFile, fnName string, ctx, finishCtx *pFuncContext) *pFuncContext {
+	// finish 函数只能有一个 AsyncResult 类型的参数
+	if len(finishCtx.params) != 1 || !strings.HasSuffix(finishCtx.params[0], "AsyncResult") {
+		return nil
+	}
+	// 找出回调中 AsyncResult 类型的参数
+	resIdx := -1
+	for idx, param := range ctx.asyncCbParams {
+		if strings.HasSuffix(param, "AsyncResult") {
+			resIdx = idx
+			break
+		}
+	}
+	if resIdx == -1 {
+		return nil
+	}
+
+	var varReg VarReg
+	varV := varReg.alloc("v")
+
+	// 回调参数之前和之后的实参
+	var params []string
+	var argsBefore, argsAfter []string
+	foundCb := false
+	for _, param := range ctx.params {
+		parts := strings.SplitN(param, " ", 2)
+		paramName := varReg.alloc(parts[0])
+		if parts[0] == ctx.asyncCbParam {
+			// 回调参数由包装函数提供
+			foundCb = true
+			continue
+		}
+		params = append(params, paramName+" "+parts[1])
+		if foundCb {
+			argsAfter = append(argsAfter, paramName)
+		} else {
+			argsBefore = append(argsBefore, paramName)
+		}
+	}
+	if !foundCb {
+		return nil
+	}
+
+	var retParams []string
+	var retNames []string
+	for _, retParam := range finishCtx.retParams {
+		parts := strings.SplitN(retParam, " ", 2)
+		retName := varReg.alloc(parts[0])
+		retParams = append(retParams, retName+" "+parts[1])
+		retNames = append(retNames, retName)
+	}
+
+	var cbParams []string
+	var varRes string
+	for idx, param := range ctx.asyncCbParams {
+		parts := strings.SplitN(param, " ", 2)
+		paramName := varReg.alloc(parts[0])
+		if idx == resIdx {
+			varRes = paramName
+		}
+		cbParams = append(cbParams, paramName+" "+parts[1])
+	}
+	varDone := varReg.alloc("done")
+
+	callPrefix := ""
+	if ctx.receiver != "" {
+		callPrefix = varV + "."
+	}
+
+	retPart := strings.Join(retParams, ", ")
+	if len(retParams) > 0 {
+		retPart = "(" + retPart + ")"
+	}
+
+	s.GoBody.Pn("// %v 调用 %v 发起异步操作，并等待操作完成，然后返回 %v 的结果。",
+		fnName, ctx.symbol, finishCtx.symbol)
+	s.GoBody.Pn("// 异步操作的回调在当前线程的一个新的 main context 中执行，参考 gi.Await。")
+	s.GoBody.Pn("func %v %v(%v) %v {", ctx.receiver, fnName, strings.Join(params, ", "), retPart)
+	s.GoBody.Pn("gi.Await(func(%v func()) {", varDone)
+	argsBeforeJoined := strings.Join(argsBefore, ", ")
+	if len(argsBefore) > 0 {
+		argsBeforeJoined += ", "
+	}
+	s.GoBody.Pn("%v%v(%vfunc(%v) {", callPrefix, ctx.fnName, argsBeforeJoined, strings.Join(cbParams, ", "))
+
+	finishCall := fmt.Sprintf("%v%v(%v)", callPrefix, finishCtx.fnName, varRes)
+	if len(retNames) > 0 {
+		finishCall = strings.Join(retNames, ", ") + " = " + finishCall
+	}
+	s.GoBody.Pn(finishCall)
+	s.GoBody.Pn("%v()", varDone)
+	argsAfterJoined := ""
+	for _, arg := range argsAfter {
+		argsAfterJoined += ", " + arg
+	}
+	s.GoBody.Pn("}%v)", argsAfterJoined)
+	s.GoBody.Pn("})")
+	if len(retParams) > 0 {
+		s.GoBody.Pn("return")
+	}
+	s.GoBody.Pn("}")
+
+	return &pFuncContext{
+		container: ctx.container,
+		fnName:    fnName,
+		receiver:  ctx.receiver,
+		params:    params,
+		retParams: retParams,
+	}
+}
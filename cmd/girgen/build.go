@@ -0,0 +1,403 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/electricface/go-gir3/gi"
+	"golang.org/x/xerrors"
+)
+
+/*
+manifest 文件列出了 girgen build 要生成的所有命名空间，比如：
+
+	{
+		"Packages": [
+			{
+				"Package": "g",
+				"Dir": "g-2.0",
+				"Namespaces": [
+					{"Name": "GLib", "Version": "2.0", "Config": "glib-config.json"},
+					{"Name": "GObject", "Version": "2.0", "Config": "gobject-config.json"},
+					{"Name": "Gio", "Version": "2.0"}
+				]
+			},
+			{"Namespaces": [{"Name": "Gtk", "Version": "3.0"}]}
+		]
+	}
+
+一个包可以合并多个命名空间，比如 g 包合并了 GLib, GObject 和 Gio，包中的第一个命名空间负责同步 lib.in 中的文件，
+所以其它命名空间总是在它之后生成。
+*/
+type manifest struct {
+	Packages []*manifestPackage
+}
+
+type manifestPackage struct {
+	Package    string // Go 包名，默认是第一个命名空间名的小写
+	Dir        string // 输出目录名，默认是第一个命名空间的 "名字-版本" 的小写，比如 gtk-3.0
+	Namespaces []*manifestNamespace
+}
+
+type manifestNamespace struct {
+	Name    string
+	Version string
+	File    string // 输出文件名，默认是命名空间名的小写加上 _auto.go，比如 gtk_auto.go
+	Config  string // 配置文件名，默认是 config.json
+}
+
+func loadManifest(filename string) (*manifest, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&m)
+	if err != nil {
+		return nil, xerrors.Errorf("decode manifest file %v: %w", filename, err)
+	}
+	err = m.normalize()
+	if err != nil {
+		return nil, xerrors.Errorf("invalid manifest file %v: %w", filename, err)
+	}
+	return &m, nil
+}
+
+// normalize 检查 manifest 并填充默认值
+func (m *manifest) normalize() error {
+	seen := make(map[string]struct{})
+	for _, pkg := range m.Packages {
+		if len(pkg.Namespaces) == 0 {
+			return xerrors.Errorf("package %q has no namespaces", pkg.Package)
+		}
+		for _, ns := range pkg.Namespaces {
+			if ns.Name == "" || ns.Version == "" {
+				return xerrors.Errorf("namespace name and version are required, got %q %q",
+					ns.Name, ns.Version)
+			}
+			nsVer := ns.nameVersion()
+			if _, ok := seen[nsVer]; ok {
+				return xerrors.Errorf("duplicate namespace %v", nsVer)
+			}
+			seen[nsVer] = struct{}{}
+			if ns.File == "" {
+				ns.File = strings.ToLower(ns.Name) + "_auto.go"
+			}
+		}
+		first := pkg.Namespaces[0]
+		if pkg.Package == "" {
+			pkg.Package = strings.ToLower(first.Name)
+		}
+		if pkg.Dir == "" {
+			pkg.Dir = strings.ToLower(first.nameVersion())
+		}
+	}
+	return nil
+}
+
+func (ns *manifestNamespace) nameVersion() string {
+	return ns.Name + "-" + ns.Version
+}
+
+// getNamespaceConfigFile 返回命名空间 ns 的配置文件，它在输出目录 pkgRoot 下的包目录中，或者在 lib.in 中。
+// m 为 nil 或者其中没有此命名空间时按默认的布局查找：GLib、GObject 和 Gio 在 g-2.0 中，
+// 配置文件名是命名空间名的小写加上 -config.json 或者 config.json；其它的在 "名字-版本" 的小写中，
+// 配置文件名是 config.json。都找不到时返回包目录中的 config.json。
+func getNamespaceConfigFile(m *manifest, pkgRoot, ns, version string) string {
+	var dir string
+	var names []string
+	if m != nil {
+		for _, pkg := range m.Packages {
+			for _, mns := range pkg.Namespaces {
+				if mns.Name == ns && mns.Version == version {
+					dir = pkg.Dir
+					names = []string{"config.json"}
+					if mns.Config != "" {
+						names[0] = mns.Config
+					}
+				}
+			}
+		}
+	}
+	if dir == "" {
+		if isGNamespace(ns) {
+			dir = "g-2.0"
+			names = []string{strings.ToLower(ns) + "-config.json", "config.json"}
+		} else {
+			dir = strings.ToLower(ns + "-" + version)
+			names = []string{"config.json"}
+		}
+	}
+
+	for _, name := range names {
+		for _, baseDir := range []string{filepath.Join(pkgRoot, dir), filepath.Join("lib.in", dir)} {
+			filename := filepath.Join(baseDir, name)
+			if _, err := os.Stat(filename); err == nil {
+				return filename
+			}
+		}
+	}
+	return filepath.Join(pkgRoot, dir, names[len(names)-1])
+}
+
+// buildJob 是生成一个命名空间的任务
+type buildJob struct {
+	pkg        *manifestPackage
+	ns         *manifestNamespace
+	deps       []*buildJob // 必须在此任务之前完成的任务
+	dependents []*buildJob // 依赖此任务的任务
+}
+
+func (job *buildJob) String() string {
+	return job.ns.nameVersion()
+}
+
+func (job *buildJob) addDep(dep *buildJob) {
+	for _, d := range job.deps {
+		if d == dep {
+			return
+		}
+	}
+	job.deps = append(job.deps, dep)
+	dep.dependents = append(dep.dependents, job)
+}
+
+// getBuildJobs 根据 manifest 创建任务，getDeps 返回命名空间的所有依赖，元素是 "名字-版本"。
+func getBuildJobs(m *manifest, getDeps func(ns *manifestNamespace) []string) []*buildJob {
+	var jobs []*buildJob
+	jobMap := make(map[string]*buildJob)
+	for _, pkg := range m.Packages {
+		for _, ns := range pkg.Namespaces {
+			job := &buildJob{pkg: pkg, ns: ns}
+			jobs = append(jobs, job)
+			jobMap[ns.nameVersion()] = job
+		}
+	}
+
+	for _, job := range jobs {
+		deps := getDeps(job.ns)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			depJob := jobMap[dep]
+			if depJob == nil {
+				log.Printf("WARN: %v depends on %v, which is not in the manifest", job, dep)
+				continue
+			}
+			job.addDep(depJob)
+		}
+		// 合并的包中的第一个命名空间负责同步文件，其它的要在它之后。
+		first := job.pkg.Namespaces[0]
+		if job.ns != first {
+			job.addDep(jobMap[first.nameVersion()])
+		}
+	}
+	return jobs
+}
+
+// sortBuildJobs 按依赖关系对任务做拓扑排序，依赖在前，有循环依赖则返回错误。
+func sortBuildJobs(jobs []*buildJob) ([]*buildJob, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*buildJob]int, len(jobs))
+	result := make([]*buildJob, 0, len(jobs))
+	var visit func(job *buildJob, path []string) error
+	visit = func(job *buildJob, path []string) error {
+		path = append(path, job.String())
+		switch state[job] {
+		case visiting:
+			return xerrors.Errorf("dependency cycle: %v", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		state[job] = visiting
+		for _, dep := range job.deps {
+			err := visit(dep, path)
+			if err != nil {
+				return err
+			}
+		}
+		state[job] = visited
+		result = append(result, job)
+		return nil
+	}
+	for _, job := range jobs {
+		err := visit(job, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+type buildJobResult struct {
+	job *buildJob
+	err error
+}
+
+// runBuildJobs 运行所有任务，一个任务在它依赖的任务都成功后才开始，最多同时运行 numWorkers 个任务。
+// 有任务失败后不再开始新的任务，返回所有失败任务的错误。jobs 必须是 sortBuildJobs 的结果。
+func runBuildJobs(jobs []*buildJob, numWorkers int, run func(job *buildJob) error) error {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	// 键是任务，值是它还没完成的依赖任务的个数
+	numPending := make(map[*buildJob]int, len(jobs))
+	var ready []*buildJob
+	for _, job := range jobs {
+		numPending[job] = len(job.deps)
+		if len(job.deps) == 0 {
+			ready = append(ready, job)
+		}
+	}
+
+	resultCh := make(chan buildJobResult)
+	running := 0
+	var failures []string
+	for {
+		for len(ready) > 0 && running < numWorkers && len(failures) == 0 {
+			job := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				resultCh <- buildJobResult{job: job, err: run(job)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		result := <-resultCh
+		running--
+		if result.err != nil {
+			failures = append(failures, fmt.Sprintf("%v: %v", result.job, result.err))
+			continue
+		}
+		for _, dependent := range result.job.dependents {
+			numPending[dependent]--
+			if numPending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return xerrors.Errorf("failed to build:\n%v", strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// runBuild 处理 girgen build 子命令。由于生成代码时用了很多全局状态，每个命名空间都在子进程中生成。
+func runBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	manifestFile := fs.String("m", "manifest.json", "manifest file")
+	numWorkers := fs.Int("j", runtime.NumCPU(), "number of namespaces to generate concurrently")
+	minVersion := fs.String("min-version", "", "minimum library version to support, passed to every namespace")
+	unsupported := fs.String("unsupported", "", "how to handle unsupported functions: keep, omit or stub, passed to every namespace")
+	reportDir := fs.String("report-dir", "", "write a JSON report for every namespace to this directory, named like Gtk-3.0.json")
+	reportHtml := fs.Bool("report-html", false, "also write an HTML report for every namespace to the report directory")
+	// 也可以在 build 之前指定
+	fs.Var(&_optGirDirs, "gir-dir", "directory to search for .gir files")
+	fs.Var(&_optTypelibDirs, "typelib-dir", "directory to search for .typelib files")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	setupSearchPath()
+
+	m, err := loadManifest(*manifestFile)
+	if err != nil {
+		return err
+	}
+
+	repo := gi.DefaultRepository()
+	jobs := getBuildJobs(m, func(ns *manifestNamespace) []string {
+		// getAllDeps 会加载此命名空间
+		return getAllDeps(repo, ns.nameVersion())
+	})
+	jobs, err = sortBuildJobs(jobs)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	pkgRoot := getPkgRootDir()
+
+	return runBuildJobs(jobs, *numWorkers, func(job *buildJob) error {
+		cmdArgs := []string{
+			"-n", job.ns.Name,
+			"-v", job.ns.Version,
+			"-p", job.pkg.Package,
+			"-f", filepath.Join(pkgRoot, job.pkg.Dir, job.ns.File),
+			"-manifest", *manifestFile,
+		}
+		if job.ns.Config != "" {
+			cmdArgs = append(cmdArgs, "-c", job.ns.Config)
+		}
+		if *minVersion != "" {
+			cmdArgs = append(cmdArgs, "-min-version", *minVersion)
+		}
+		if *unsupported != "" {
+			cmdArgs = append(cmdArgs, "-unsupported", *unsupported)
+		}
+		if isModuleMode() {
+			// go.mod 已经在 main 中更新过了
+			cmdArgs = append(cmdArgs, "-module-root", _optModuleRoot, "-module-path", _optModulePath)
+		}
+		if *reportDir != "" {
+			cmdArgs = append(cmdArgs, "-report", filepath.Join(*reportDir, job.String()+".json"))
+			if *reportHtml {
+				cmdArgs = append(cmdArgs, "-report-html", filepath.Join(*reportDir, job.String()+".html"))
+			}
+		}
+		for _, dir := range _optGirDirs {
+			cmdArgs = append(cmdArgs, "-gir-dir", dir)
+		}
+		for _, dir := range _optTypelibDirs {
+			cmdArgs = append(cmdArgs, "-typelib-dir", dir)
+		}
+		log.Printf("generate %v", job)
+		out, err := exec.Command(exe, cmdArgs...).CombinedOutput()
+		// 任务是并发运行的，输出在任务结束后一起打印，避免交错。
+		_, _ = fmt.Fprintf(os.Stderr, "==> %v\n%s", job, out)
+		return err
+	})
+}
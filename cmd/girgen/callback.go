@@ -28,20 +28,103 @@ import (
 	"github.com/electricface/go-gir3/gi"
 )
 
+// 键是回调的 "命名空间.名字"，值是 isCallbackUnsupported 的结果。
+var _callbackUnsupportedMap = make(map[string]bool)
+
 func pCallback(s *SourceFile, fi *gi.CallableInfo) {
+	name := fi.Name()
+	goImports := _sourceFile.GoImports
+	b := &SourceBlock{}
 	// DestroyNotify
-	pCallbackFuncDefine(s.GoBody, fi)
-
+	todo := pCallbackFuncDefine(b, fi)
 	// CallDestroyNotify
-	pCallCallback(s.GoBody, fi)
+	pCallCallback(b, fi)
+
+	unsupported := b.containsTodo()
+	_callbackUnsupportedMap[fi.Namespace()+"."+name] = unsupported
+	if unsupported {
+		if todo == nil {
+			todo = &todoItem{Kind: "callback", Name: name}
+		}
+		addTodoItem(todo)
+		// CallXxx 函数中的 TODO 也加入报告
+		addTodoItem(&todoItem{Kind: "callback", Name: name, Details: getTodoDetails(b.buf.String())})
+
+		switch _cfg.Unsupported {
+		case unsupportedOmit:
+			// 丢弃的代码添加的导入可能不再被使用
+			_sourceFile.GoImports = goImports
+			s.GoBody.Pn("\n// omitted unsupported callback %s\n", name)
+			return
+		case unsupportedStub:
+			_sourceFile.GoImports = goImports
+			b = &SourceBlock{}
+			pCallbackStub(b, fi)
+		}
+	}
+
+	pDoc(s.GoBody, getTypeDoc(name))
+	s.GoBody.addBlock(b)
+}
+
+// isCallbackUnsupported 返回是否不能正确处理回调 fi，即为它生成的函数类型或 CallXxx 函数中含有 TODO。
+func isCallbackUnsupported(fi *gi.CallableInfo) bool {
+	key := fi.Namespace() + "." + fi.Name()
+	if unsupported, ok := _callbackUnsupportedMap[key]; ok {
+		return unsupported
+	}
+	// 只是检查生成的代码，不保留生成时添加的导入
+	goImports := _sourceFile.GoImports
+	b := &SourceBlock{}
+	pCallbackFuncDefine(b, fi)
+	pCallCallback(b, fi)
+	_sourceFile.GoImports = goImports
+	unsupported := b.containsTodo()
+	_callbackUnsupportedMap[key] = unsupported
+	return unsupported
+}
+
+// isCallbackOmitted 返回是否因为不能正确处理回调 fi 而没有生成它的函数类型和 CallXxx 函数，或者只生成了桩，
+// 使用回调所属的命名空间的 Unsupported 配置。
+func isCallbackOmitted(fi *gi.CallableInfo) bool {
+	switch getUnsupportedMode(fi.Namespace()) {
+	case unsupportedOmit, unsupportedStub:
+		return isCallbackUnsupported(fi)
+	}
+	return false
 }
 
-func pCallCallback(b *SourceBody, fi *gi.CallableInfo) {
+// pCallbackStub 打印不能正确处理的回调 fi 的桩，函数类型中不含类型不能处理的参数和返回值，
+// CallXxx 函数不调用 Go 函数，只返回 gi.ErrNotSupported。
+func pCallbackStub(b *SourceBlock, fi *gi.CallableInfo) {
 	var varReg VarReg
 	varFn := varReg.alloc("fn")
 	varResult := varReg.alloc("result")
 	varArgs := varReg.alloc("args")
-	fiName := fi.Name()
+	varErr := varReg.alloc("err")
+	name := getTypeName(fi.Name())
+	paramNameTypes, retNameTypes, _ := getCallbackParams(fi)
+	retNameTypes = removeTodoParams(retNameTypes)
+	retPart := ""
+	if len(retNameTypes) > 0 {
+		retPart = "(" + strings.Join(retNameTypes, ", ") + ")"
+	}
+	b.Pn("//\n// unsupported callback")
+	b.Pn("type %v func(%v) %v", name, strings.Join(removeTodoParams(paramNameTypes), ", "), retPart)
+	b.Pn("\n// unsupported callback, always returns gi.ErrNotSupported")
+	b.Pn("func Call%v(%v %v, %v unsafe.Pointer, %v []unsafe.Pointer) (%v error) {", name, varFn, name,
+		varResult, varArgs, varErr)
+	b.Pn("%v = gi.ErrNotSupported", varErr)
+	b.Pn("return")
+	b.Pn("}") // end func
+}
+
+func pCallCallback(b *SourceBlock, fi *gi.CallableInfo) {
+	var varReg VarReg
+	varFn := varReg.alloc("fn")
+	varResult := varReg.alloc("result")
+	varArgs := varReg.alloc("args")
+	fiName := getTypeName(fi.Name())
 	b.Pn("func Call%v(%v %v, %v unsafe.Pointer, %v []unsafe.Pointer) {", fiName, varFn, fiName, varResult, varArgs)
 	b.Pn("if %v == nil {\nreturn\n}", varFn)
 
@@ -107,11 +190,28 @@ func pCallCallback(b *SourceBody, fi *gi.CallableInfo) {
 	b.Pn("}")
 }
 
-func pCallbackFuncDefine(b *SourceBody, fi *gi.CallableInfo) {
-	name := fi.Name()
+// pCallbackFuncDefine 打印回调 fi 对应的 Go 函数类型，如果类型中含有 TODO，返回报告中的一项，否则返回 nil。
+func pCallbackFuncDefine(b *SourceBlock, fi *gi.CallableInfo) *todoItem {
+	name := getTypeName(fi.Name())
+	paramNameTypes, retNameTypes, todoReasons := getCallbackParams(fi)
 
-	var paramNameTypes []string
-	var retNameTypes []string
+	argsPart := strings.Join(paramNameTypes, ", ")
+	retPart := ""
+	if len(retNameTypes) > 0 {
+		retPart = "(" + strings.Join(retNameTypes, ", ") + ")"
+	}
+	b.Pn("type %v func(%v) %v", name, argsPart, retPart)
+
+	details := getTodoDetails(argsPart + retPart)
+	if len(todoReasons) == 0 && len(details) == 0 {
+		return nil
+	}
+	return &todoItem{Kind: "callback", Name: fi.Name(), Reasons: todoReasons, Details: details}
+}
+
+// getCallbackParams 获取回调 fi 对应的 Go 函数类型的参数列表和返回值列表，元素都是 "名字 类型"，
+// 还有导致类型中含有 TODO 的参数和返回值。
+func getCallbackParams(fi *gi.CallableInfo) (paramNameTypes, retNameTypes []string, todoReasons []todoReason) {
 	var varReg VarReg
 
 	retType := fi.ReturnType()
@@ -121,6 +221,9 @@ func pCallbackFuncDefine(b *SourceBody, fi *gi.CallableInfo) {
 	if retResult.goType != "" {
 		retNameTypes = append(retNameTypes, varResult+" "+retResult.goType)
 	}
+	if strings.Contains(retResult.goType, "TODO") {
+		todoReasons = append(todoReasons, newRetTodoReason(fi))
+	}
 
 	numArgs := fi.NumArg()
 	for i := 0; i < numArgs; i++ {
@@ -129,9 +232,11 @@ func pCallbackFuncDefine(b *SourceBody, fi *gi.CallableInfo) {
 
 		paramName := varReg.registerParam(i, argInfo.Name())
 		dir := argInfo.Direction()
+		var goType string
 		switch dir {
 		case gi.DIRECTION_IN:
 			result := parseCbArgTypeDirIn(paramName, argTypeInfo, argInfo, "", i)
+			goType = result.goType
 			if result.goType != "" {
 				if result.isRet {
 					retNameTypes = append(retNameTypes, paramName+" "+result.goType)
@@ -141,19 +246,18 @@ func pCallbackFuncDefine(b *SourceBody, fi *gi.CallableInfo) {
 			}
 		case gi.DIRECTION_OUT:
 			result := parseCbArgTypeDirOut(paramName, argTypeInfo, "", "")
+			goType = result.goType
 			retNameTypes = append(retNameTypes, paramName+" "+result.goType)
 		case gi.DIRECTION_INOUT:
 			result := parseCbArgTypeDirInOut(paramName, argTypeInfo, "")
+			goType = result.goType
 			paramNameTypes = append(paramNameTypes, paramName+" "+result.goType)
 		}
+		if strings.Contains(goType, "TODO") {
+			todoReasons = append(todoReasons, newArgTodoReason(argInfo))
+		}
 	}
-
-	argsPart := strings.Join(paramNameTypes, ", ")
-	retPart := ""
-	if len(retNameTypes) > 0 {
-		retPart = "(" + strings.Join(retNameTypes, ", ") + ")"
-	}
-	b.Pn("type %v func(%v) %v", name, argsPart, retPart)
+	return
 }
 
 type parseCbRetResult struct {
@@ -398,7 +502,7 @@ func parseCbArgTypeDirIn(paramName string, argTypeInfo *gi.TypeInfo, argInfo *gi
 				expr = fmt.Sprintf("%v{P: %v}", goType, ptrExpr)
 				if ifcType == gi.INFO_TYPE_OBJECT {
 					expr = fmt.Sprintf("%vWrap%v(%v)",
-						getPkgPrefix(ii.Namespace()), ii.Name(), ptrExpr)
+						getPkgPrefix(ii.Namespace()), getGoTypeName(ii.Namespace(), ii.Name()), ptrExpr)
 				}
 			}
 		}
@@ -517,6 +621,10 @@ func pCallback1(s *SourceFile, fi *gi.CallableInfo) {
 	var fieldSetLines []string
 	var handleArgs []string
 
+	// 报告中的原因，和生成的代码中的 TODO 一起加入报告
+	var todoReasons []todoReason
+	start := s.GoBody.buf.Len()
+
 	var varReg VarReg
 	numArgs := fi.NumArg()
 	foundUserData := false
@@ -530,6 +638,9 @@ func pCallback1(s *SourceFile, fi *gi.CallableInfo) {
 		switch dir {
 		case gi.DIRECTION_IN:
 			parseResult := parseCbArgTypeDirIn1(paramName, argTypeInfo)
+			if strings.Contains(parseResult.cgoType+parseResult.goType+parseResult.expr, "TODO") {
+				todoReasons = append(todoReasons, newArgTodoReason(argInfo))
+			}
 			paramNameTypes = append(paramNameTypes, paramName+" "+parseResult.cgoType)
 			cParamTypeNames = append(cParamTypeNames, parseResult.cType+" "+paramName)
 
@@ -547,6 +658,9 @@ func pCallback1(s *SourceFile, fi *gi.CallableInfo) {
 
 		case gi.DIRECTION_OUT:
 			parseResult := parseCbArgTypeDirOut1(paramName, argTypeInfo)
+			if strings.Contains(parseResult.cgoType+parseResult.goType+parseResult.expr, "TODO") {
+				todoReasons = append(todoReasons, newArgTodoReason(argInfo))
+			}
 			paramNameTypes = append(paramNameTypes, paramName+" "+parseResult.cgoType)
 			cParamTypeNames = append(cParamTypeNames, parseResult.cType+" "+paramName)
 
@@ -558,6 +672,9 @@ func pCallback1(s *SourceFile, fi *gi.CallableInfo) {
 
 		case gi.DIRECTION_INOUT:
 			parseResult := parseCbArgTypeDirInOut1(paramName, argTypeInfo)
+			if strings.Contains(parseResult.cgoType+parseResult.goType+parseResult.expr, "TODO") {
+				todoReasons = append(todoReasons, newArgTodoReason(argInfo))
+			}
 			paramNameTypes = append(paramNameTypes, paramName+" "+parseResult.cgoType)
 			cParamTypeNames = append(cParamTypeNames, parseResult.cType+" "+paramName)
 
@@ -576,6 +693,9 @@ func pCallback1(s *SourceFile, fi *gi.CallableInfo) {
 	defer retType.Unref()
 	varResult := varReg.alloc("result")
 	retResult := parseCbRet1(retType, varResult)
+	if strings.Contains(retResult.cgoType+retResult.goType+retResult.expr, "TODO") {
+		todoReasons = append(todoReasons, newRetTodoReason(fi))
+	}
 
 	wrapperFuncName := "gi" + _optNamespace + name
 
@@ -641,7 +761,7 @@ func pCallback1(s *SourceFile, fi *gi.CallableInfo) {
 
 	} else {
 		// 没有 user_data 参数
-		if strSliceContains(_cfg.ManualCallbacks, name) {
+		if _cfg.isManualCallback(name) {
 			s.GoBody.Pn("handleDestroyNotify(%v)", strings.Join(handleArgs, ", "))
 		} else {
 			// TODO
@@ -654,6 +774,16 @@ func pCallback1(s *SourceFile, fi *gi.CallableInfo) {
 	}
 
 	s.GoBody.Pn("}") // end func
+
+	// 和函数、字段、信号、虚函数一样，生成的代码中含有 TODO 时加入报告。
+	code := s.GoBody.buf.String()[start:]
+	if strings.Contains(code, "TODO") {
+		details := getTodoDetails(code)
+		if strings.Contains(code, "TODO: not found user_data") {
+			details = append(details, "not found user_data")
+		}
+		addTodoItem(&todoItem{Kind: "callback", Name: name, Reasons: todoReasons, Details: details})
+	}
 }
 
 type parseCbRetResult1 struct {
@@ -956,7 +1086,7 @@ func parseCbArgTypeDirIn1(paramName string, argTypeInfo *gi.TypeInfo) *parseCbAr
 				expr = fmt.Sprintf("%v{P: unsafe.Pointer(%v) }", goType, paramName)
 				if ifcType == gi.INFO_TYPE_OBJECT {
 					expr = fmt.Sprintf("%vWrap%v(unsafe.Pointer(%v))",
-						getPkgPrefix(ii.Namespace()), name, paramName)
+						getPkgPrefix(ii.Namespace()), getGoTypeName(ii.Namespace(), ii.Name()), paramName)
 				}
 			}
 		}
@@ -22,10 +22,16 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/xerrors"
 )
 
 type config struct {
@@ -37,6 +43,52 @@ type config struct {
 	CPkgList            []string
 	NoGetType           []string // 不自动生成 GetType 方法的类型列表。
 	ManualCallbacks     []string // 用手写代码处理的 callback 名称列表
+	// 生成的代码需要支持的最低库版本，比如 "2.56"，GIR 中 version 晚于此版本的 API 不生成，为空则不限制。
+	MinVersion string
+	// 重命名表，键是 C 函数的符号名或 GIR 中的类型名，值是 Go 中的名字，比如 "g_uri_parse": "ParseURI"。
+	// 引用其它命名空间的类型时使用它们自己的配置中的重命名，这里也可以用加上命名空间前缀的键补充，比如 "GLib.Uri"。
+	Renames map[string]string
+	// 首字母缩略词，比如 "URI", "ID", "DBus", "HTTP"，名字中的 Uri, Id, Dbus, Http 等单词会被替换为它们，
+	// 作用于类型、方法、字段、常量和信号的名字，引用其它命名空间的类型时使用它们自己的配置中的首字母缩略词。
+	Initialisms []string
+	// 命名空间（不含版本）到 Go 包的映射，用于引用其它命名空间的类型和导入包，
+	// 比如 "Gtk": {"Path": "example.com/foo/gtk-3.0", "Name": "gtk"}，Name 可省略。
+	// 没有配置的命名空间在 GIR_PKG_PATH 下，GLib、GObject 和 Gio 在 g-2.0 中。
+	Packages map[string]*goPackage
+	// 如何处理生成器不能正确处理参数或返回值的函数，"keep" 或空是照常生成含有 TODO 的函数，
+	// "omit" 是不生成，"stub" 是生成返回 gi.ErrNotSupported 的桩函数，后两种函数都会列在报告中。
+	// 类型含有 TODO 的回调和字段的存取方法也同样处理。
+	Unsupported string
+}
+
+// Unsupported 配置项的值
+const (
+	unsupportedKeep = "keep"
+	unsupportedOmit = "omit"
+	unsupportedStub = "stub"
+)
+
+// 依赖的命名空间的 Unsupported 配置，键是命名空间名（不含版本），和命名规则一起由 loadDepNaming 加载。
+var _depUnsupported = make(map[string]string)
+
+// getUnsupportedMode 返回命名空间 ns 的 Unsupported 配置，-unsupported 参数优先。
+// 用于判断依赖的命名空间中不能正确处理的回调有没有生成。
+func getUnsupportedMode(ns string) string {
+	if _optUnsupported != "" {
+		return _optUnsupported
+	}
+	if isSameNamespace(ns) {
+		return _cfg.Unsupported
+	}
+	return _depUnsupported[ns]
+}
+
+func isValidUnsupportedMode(mode string) bool {
+	switch mode {
+	case "", unsupportedKeep, unsupportedOmit, unsupportedStub:
+		return true
+	}
+	return false
 }
 
 func loadConfig(filename string, cfg *config) error {
@@ -47,31 +99,171 @@ func loadConfig(filename string, cfg *config) error {
 		}
 		return err
 	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// 拒绝未知的键，避免拼写错误的配置项被静默忽略。
+	dec.DisallowUnknownFields()
+	err = dec.Decode(cfg)
+	if err != nil {
+		return xerrors.Errorf("decode config file %v: %w", filename, err)
+	}
+	if dec.More() {
+		return xerrors.Errorf("decode config file %v: unexpected data after config", filename)
+	}
+
+	err = cfg.validate()
+	if err != nil {
+		return xerrors.Errorf("invalid config file %v: %w", filename, err)
+	}
 	log.Printf("load config %v ok", filename)
-	return json.Unmarshal(data, cfg)
+	return nil
 }
 
-type genState struct {
-	PrevNamespace string
-	FuncNextId    int
-	GetTypeNextId int
+type configList struct {
+	key  string
+	list []string
 }
 
-func loadGenState(filename string, genState *genState) error {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+// nameLists 返回元素是 GIR 中的名字的配置项列表
+func (cfg *config) nameLists() []configList {
+	return []configList{
+		{"DeniedFuncs", cfg.DeniedFuncs},
+		{"DeniedFieldsStructs", cfg.DeniedFieldsStructs},
+		{"DeniedFields", cfg.DeniedFields},
+		{"NoGetType", cfg.NoGetType},
+		{"ManualCallbacks", cfg.ManualCallbacks},
+	}
+}
+
+// validate 检查配置项的值是否合法
+func (cfg *config) validate() error {
+	lists := append(cfg.nameLists(), configList{"Initialisms", cfg.Initialisms})
+	for _, l := range lists {
+		seen := make(map[string]struct{}, len(l.list))
+		for _, item := range l.list {
+			if item == "" || strings.ContainsAny(item, " \t\n") {
+				return xerrors.Errorf("%v: invalid entry %q", l.key, item)
+			}
+			if _, ok := seen[item]; ok {
+				return xerrors.Errorf("%v: duplicate entry %q", l.key, item)
+			}
+			seen[item] = struct{}{}
+		}
+	}
+
+	for _, item := range cfg.DeniedFields {
+		parts := strings.Split(item, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return xerrors.Errorf("DeniedFields: invalid entry %q, expect Struct.field", item)
 		}
-		return err
 	}
-	return json.Unmarshal(data, genState)
+
+	for _, initialism := range cfg.Initialisms {
+		if !isGoIdentifier(initialism) {
+			return xerrors.Errorf("Initialisms: invalid entry %q", initialism)
+		}
+	}
+
+	for key, goName := range cfg.Renames {
+		if key == "" {
+			return xerrors.New("Renames: empty key")
+		}
+		if !isGoIdentifier(goName) {
+			return xerrors.Errorf("Renames: invalid Go name %q for %q", goName, key)
+		}
+	}
+
+	for ns, pkg := range cfg.Packages {
+		if ns == "" || strings.Contains(ns, "-") {
+			return xerrors.Errorf("Packages: invalid namespace %q, expect namespace without version", ns)
+		}
+		if pkg == nil || pkg.Path == "" {
+			return xerrors.Errorf("Packages: empty import path for %q", ns)
+		}
+		if pkg.Name != "" && !isGoIdentifier(pkg.Name) {
+			return xerrors.Errorf("Packages: invalid package name %q for %q", pkg.Name, ns)
+		}
+	}
+
+	if !isValidUnsupportedMode(cfg.Unsupported) {
+		return xerrors.Errorf("Unsupported: invalid mode %q, expect keep, omit or stub", cfg.Unsupported)
+	}
+
+	if cfg.MinVersion != "" && !isValidVersion(cfg.MinVersion) {
+		return xerrors.Errorf("MinVersion: invalid version %q", cfg.MinVersion)
+	}
+	return nil
 }
 
-func saveGenState(filename string, genState *genState) error {
-	data, err := json.Marshal(genState)
-	if err != nil {
-		return err
+// 配置项的名字和列表里的某一项，用于记录它是否匹配过。
+type configEntry struct {
+	key  string
+	item string
+}
+
+// 记录匹配过的配置项
+var _matchedConfigEntries = make(map[configEntry]struct{})
+
+// matchConfigList 返回 name 是否在配置项 key 的列表 list 中，并记录匹配过的项。
+func matchConfigList(key string, list []string, name string) bool {
+	if !strSliceContains(list, name) {
+		return false
+	}
+	_matchedConfigEntries[configEntry{key: key, item: name}] = struct{}{}
+	return true
+}
+
+func (cfg *config) isFuncDenied(name string) bool {
+	return matchConfigList("DeniedFuncs", cfg.DeniedFuncs, name)
+}
+
+func (cfg *config) isFieldsStructDenied(name string) bool {
+	return matchConfigList("DeniedFieldsStructs", cfg.DeniedFieldsStructs, name)
+}
+
+func (cfg *config) isFieldDenied(name string) bool {
+	return matchConfigList("DeniedFields", cfg.DeniedFields, name)
+}
+
+func (cfg *config) isNoGetType(name string) bool {
+	return matchConfigList("NoGetType", cfg.NoGetType, name)
+}
+
+func (cfg *config) isManualCallback(name string) bool {
+	return matchConfigList("ManualCallbacks", cfg.ManualCallbacks, name)
+}
+
+// getUnmatchedEntries 返回没有匹配过任何东西的配置项，一般是过时的或者写错了的。
+func (cfg *config) getUnmatchedEntries() []configEntry {
+	var result []configEntry
+	for _, l := range cfg.nameLists() {
+		for _, item := range l.list {
+			entry := configEntry{key: l.key, item: item}
+			if _, ok := _matchedConfigEntries[entry]; !ok {
+				result = append(result, entry)
+			}
+		}
+	}
+	return result
+}
+
+func isGoIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidVersion(version string) bool {
+	for _, part := range strings.Split(version, ".") {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return false
+		}
 	}
-	return ioutil.WriteFile(filename, data, 0644)
+	return true
 }
@@ -0,0 +1,322 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/electricface/go-gir3/gi"
+)
+
+// ptrElemType 表示存放在 GHashTable，GList 和 GSList 中的元素类型，这些元素都是 gpointer。
+type ptrElemType struct {
+	type0      string // 元素在 Go 中的类型
+	fromPtrFmt string // 把 unsafe.Pointer 转换为 Go 值的表达式格式
+	toPtrFmt   string // 把 Go 值转换为 unsafe.Pointer 的表达式格式
+	isStr      bool   // 元素是否为字符串，转换为指针时会分配内存
+	isRef      bool   // 元素是否为对象或结构体等引用类型
+}
+
+func (e *ptrElemType) fromPtr(ptr string) string {
+	return fmt.Sprintf(e.fromPtrFmt, ptr)
+}
+
+func (e *ptrElemType) toPtr(val string) string {
+	return fmt.Sprintf(e.toPtrFmt, val)
+}
+
+// 解析元素的类型，返回 nil 表示不支持。
+// 元素类型为 gpointer 的容器不做转换，比如 GLib 中 g_list_append 等操作列表本身的函数。
+func parsePtrElemType(ti *gi.TypeInfo) *ptrElemType {
+	tag := ti.Tag()
+	switch tag {
+	case gi.TYPE_TAG_UTF8, gi.TYPE_TAG_FILENAME:
+		return &ptrElemType{
+			type0:      "string",
+			fromPtrFmt: "gi.GoString(%v)",
+			toPtrFmt:   "gi.CString(%v)",
+			isStr:      true,
+		}
+
+	case gi.TYPE_TAG_BOOLEAN:
+		return &ptrElemType{
+			type0:      "bool",
+			fromPtrFmt: "uintptr(%v) != 0",
+			toPtrFmt:   "gi.Uint2Ptr(uint(gi.Bool2Int(%v)))",
+		}
+
+	case gi.TYPE_TAG_INT8, gi.TYPE_TAG_UINT8,
+		gi.TYPE_TAG_INT16, gi.TYPE_TAG_UINT16,
+		gi.TYPE_TAG_INT32, gi.TYPE_TAG_UINT32,
+		gi.TYPE_TAG_INT64, gi.TYPE_TAG_UINT64,
+		gi.TYPE_TAG_UNICHAR:
+		// 整数使用 GINT_TO_POINTER 的方式存放
+		type0 := getTypeWithTag(tag)
+		return &ptrElemType{
+			type0:      type0,
+			fromPtrFmt: type0 + "(uintptr(%v))",
+			toPtrFmt:   "gi.Uint2Ptr(uint(%v))",
+		}
+
+	case gi.TYPE_TAG_GTYPE:
+		return &ptrElemType{
+			type0:      "gi.GType",
+			fromPtrFmt: "gi.GType(uintptr(%v))",
+			toPtrFmt:   "gi.Uint2Ptr(uint(%v))",
+		}
+
+	case gi.TYPE_TAG_INTERFACE:
+		bi := ti.Interface()
+		defer bi.Unref()
+		biType := bi.Type()
+		switch biType {
+		case gi.INFO_TYPE_OBJECT, gi.INFO_TYPE_INTERFACE,
+			gi.INFO_TYPE_STRUCT, gi.INFO_TYPE_UNION, gi.INFO_TYPE_BOXED:
+			if ti.IsPointer() {
+				type0 := getTypeNameWithBaseInfo(bi)
+				return &ptrElemType{
+					type0:      type0,
+					fromPtrFmt: type0 + "{P: %v}",
+					toPtrFmt:   "%v.P",
+					isRef:      true,
+				}
+			}
+
+		case gi.INFO_TYPE_ENUM, gi.INFO_TYPE_FLAGS:
+			type0 := getTypeNameWithBaseInfo(bi)
+			if biType == gi.INFO_TYPE_ENUM {
+				type0 = getEnumTypeName(type0)
+			} else {
+				type0 = getFlagsTypeName(type0)
+			}
+			return &ptrElemType{
+				type0:      type0,
+				fromPtrFmt: type0 + "(uintptr(%v))",
+				toPtrFmt:   "gi.Uint2Ptr(uint(%v))",
+			}
+		}
+	}
+	return nil
+}
+
+// 解析 GHashTable 类型的键和值的类型，ok 为 false 表示不支持。
+func parseHashTableType(ti *gi.TypeInfo) (keyElem, valueElem *ptrElemType, ok bool) {
+	keyTypeInfo := ti.ParamType(0)
+	if keyTypeInfo.IsNil() {
+		// 没有标注键和值的类型
+		return
+	}
+	defer keyTypeInfo.Unref()
+	valueTypeInfo := ti.ParamType(1)
+	if valueTypeInfo.IsNil() {
+		return
+	}
+	defer valueTypeInfo.Unref()
+
+	keyElem = parsePtrElemType(keyTypeInfo)
+	valueElem = parsePtrElemType(valueTypeInfo)
+	ok = keyElem != nil && valueElem != nil
+	return
+}
+
+func getMapType(keyElem, valueElem *ptrElemType) string {
+	return fmt.Sprintf("map[%v]%v", keyElem.type0, valueElem.type0)
+}
+
+// 产生把 GHashTable 转换为 Go map 的语句，ptrExpr 是 GHashTable 的指针。
+// 产生类似如下代码：
+//
+//	result = make(map[string]string)
+//	gi.HashTableForEach(ret.Pointer(), func(key, value unsafe.Pointer) {
+//		result[gi.GoString(key)] = gi.GoString(value)
+//	})
+//	gi.HashTableUnref(ret.Pointer())
+//
+// ok 为 false 表示不支持该转移方式。
+func getHashTableToMapLines(varMap, ptrExpr string, keyElem, valueElem *ptrElemType,
+	transfer gi.Transfer, varReg *VarReg) (lines []string, ok bool) {
+
+	if transfer != gi.TRANSFER_NOTHING && (keyElem.isRef || valueElem.isRef) {
+		// GHashTableUnref 时键和值的释放函数会释放 Go 值还在引用的对象和结构体，
+		// 需要先增加它们的引用计数或者复制它们，暂不支持。
+		return
+	}
+
+	varKey := varReg.alloc("key")
+	varValue := varReg.alloc("value")
+	lines = []string{
+		fmt.Sprintf("%v = make(%v)", varMap, getMapType(keyElem, valueElem)),
+		fmt.Sprintf("gi.HashTableForEach(%v, func(%v, %v unsafe.Pointer) {", ptrExpr, varKey, varValue),
+		fmt.Sprintf("%v[%v] = %v", varMap, keyElem.fromPtr(varKey), valueElem.fromPtr(varValue)),
+		"})",
+	}
+	if transfer != gi.TRANSFER_NOTHING {
+		// 调用者拥有 GHashTable，由 GHashTable 的键和值的释放函数负责释放其中的内容。
+		lines = append(lines, fmt.Sprintf("gi.HashTableUnref(%v)", ptrExpr))
+	}
+	ok = true
+	return
+}
+
+// 产生把 Go map 转换为 GHashTable 的语句，结果存放在变量 varTable 中。
+// ok 为 false 表示不支持该转移方式。
+func getMapToHashTableLines(varMap, varTable string, keyElem, valueElem *ptrElemType,
+	transfer gi.Transfer, varReg *VarReg) (beforeArgLines, afterCallLines []string, ok bool) {
+
+	if transfer == gi.TRANSFER_EVERYTHING && (keyElem.isRef || valueElem.isRef) {
+		// 需要增加对象的引用计数，暂不支持。
+		return
+	}
+
+	hashFunc := "gi.HashFuncDirect"
+	if keyElem.isStr {
+		hashFunc = "gi.HashFuncStr"
+	}
+	getDestroy := func(elem *ptrElemType) string {
+		if elem.isStr {
+			// 字符串由 GHashTable 负责释放
+			return "gi.HashDestroyFree"
+		}
+		return "gi.HashDestroyNone"
+	}
+
+	varKey := varReg.alloc("key")
+	varValue := varReg.alloc("value")
+	beforeArgLines = []string{
+		fmt.Sprintf("var %v unsafe.Pointer", varTable),
+		fmt.Sprintf("if %v != nil {", varMap),
+		fmt.Sprintf("%v = gi.NewHashTable(%v, %v, %v)", varTable, hashFunc,
+			getDestroy(keyElem), getDestroy(valueElem)),
+		fmt.Sprintf("for %v, %v := range %v {", varKey, varValue, varMap),
+		fmt.Sprintf("gi.HashTableInsert(%v, %v, %v)", varTable, keyElem.toPtr(varKey),
+			valueElem.toPtr(varValue)),
+		"}", // end for
+		"}", // end if
+	}
+
+	if transfer == gi.TRANSFER_NOTHING {
+		afterCallLines = append(afterCallLines, fmt.Sprintf("gi.HashTableUnref(%v)", varTable))
+	}
+	ok = true
+	return
+}
+
+// 解析 GList 或 GSList 类型的元素类型，ok 为 false 表示不支持。
+func parseListType(ti *gi.TypeInfo) (elem *ptrElemType, ok bool) {
+	elemTypeInfo := ti.ParamType(0)
+	if elemTypeInfo.IsNil() {
+		// 没有标注元素的类型
+		return
+	}
+	defer elemTypeInfo.Unref()
+	elem = parsePtrElemType(elemTypeInfo)
+	ok = elem != nil
+	return
+}
+
+// 获取列表在 GLib 中的类型名，List 或 SList。
+func getListTypeName(tag gi.TypeTag) string {
+	if tag == gi.TYPE_TAG_GSLIST {
+		return "SList"
+	}
+	return "List"
+}
+
+// 获取 gi 包中操作列表的函数的前缀，gi.List 或 gi.SList。
+func getListFuncPrefix(tag gi.TypeTag) string {
+	return "gi." + getListTypeName(tag)
+}
+
+// 产生把 GList 或 GSList 转换为 Go slice 的语句，ptrExpr 是列表的指针。
+// 产生类似如下代码：
+//
+//	gi.ListForEach(ret.Pointer(), func(item unsafe.Pointer) {
+//		result = append(result, Widget{P: item})
+//	})
+//	gi.ListFree(ret.Pointer())
+func getListToSliceLines(varSlice, ptrExpr string, tag gi.TypeTag, elem *ptrElemType,
+	transfer gi.Transfer, varReg *VarReg) []string {
+
+	fnPrefix := getListFuncPrefix(tag)
+	varItem := varReg.alloc("item")
+	lines := []string{
+		fmt.Sprintf("%vForEach(%v, func(%v unsafe.Pointer) {", fnPrefix, ptrExpr, varItem),
+		fmt.Sprintf("%v = append(%v, %v)", varSlice, varSlice, elem.fromPtr(varItem)),
+		"})",
+	}
+	switch transfer {
+	case gi.TRANSFER_CONTAINER:
+		lines = append(lines, fmt.Sprintf("%vFree(%v)", fnPrefix, ptrExpr))
+	case gi.TRANSFER_EVERYTHING:
+		if elem.isStr {
+			lines = append(lines, fmt.Sprintf("%vFreeFull(%v)", fnPrefix, ptrExpr))
+		} else {
+			// 对象和结构体等元素的所有权交给了生成的 Go 值
+			lines = append(lines, fmt.Sprintf("%vFree(%v)", fnPrefix, ptrExpr))
+		}
+	}
+	return lines
+}
+
+// 产生把 Go slice 转换为 GList 或 GSList 的语句，结果存放在变量 varList 中。
+// ok 为 false 表示不支持该转移方式。
+func getSliceToListLines(varSlice, varList string, tag gi.TypeTag, elem *ptrElemType,
+	transfer gi.Transfer, varReg *VarReg) (beforeArgLines, afterCallLines []string, ok bool) {
+
+	if transfer == gi.TRANSFER_EVERYTHING && elem.isRef {
+		// 需要增加对象的引用计数，暂不支持。
+		return
+	}
+
+	fnPrefix := getListFuncPrefix(tag)
+	varItems := varReg.alloc("items_" + varSlice)
+	varIdx := varReg.alloc("i")
+	varItem := varReg.alloc("item")
+	beforeArgLines = []string{
+		fmt.Sprintf("%v := make([]unsafe.Pointer, len(%v))", varItems, varSlice),
+		fmt.Sprintf("for %v, %v := range %v {", varIdx, varItem, varSlice),
+		fmt.Sprintf("%v[%v] = %v", varItems, varIdx, elem.toPtr(varItem)),
+		"}", // end for
+		// gi.NewList 或 gi.NewSList
+		fmt.Sprintf("%v := gi.New%v(%v)", varList, getListTypeName(tag), varItems),
+	}
+
+	switch transfer {
+	case gi.TRANSFER_NOTHING:
+		if elem.isStr {
+			afterCallLines = append(afterCallLines, fmt.Sprintf("%vFreeFull(%v)", fnPrefix, varList))
+		} else {
+			afterCallLines = append(afterCallLines, fmt.Sprintf("%vFree(%v)", fnPrefix, varList))
+		}
+	case gi.TRANSFER_CONTAINER:
+		// 被调用者只拥有列表本身，调用后列表可能已经被释放，所以通过 items 释放字符串元素。
+		if elem.isStr {
+			afterCallLines = append(afterCallLines,
+				fmt.Sprintf("for _, %v := range %v {", varItem, varItems),
+				fmt.Sprintf("gi.Free(%v)", varItem),
+				"}", // end for
+			)
+		}
+	}
+	ok = true
+	return
+}
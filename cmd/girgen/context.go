@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"strings"
+)
+
+// getCancellableParamIdx 返回函数 ctx 的参数中唯一的 Gio.Cancellable 类型参数的索引，没有或者有多个则返回 -1。
+func getCancellableParamIdx(ctx *pFuncContext) int {
+	idx := -1
+	for i, param := range ctx.params {
+		if strings.HasSuffix(param, "ICancellable") {
+			if idx != -1 {
+				return -1
+			}
+			idx = i
+		}
+	}
+	return idx
+}
+
+// pContextVariants 为 fnCtxs 中有 Gio.Cancellable 类型参数的函数生成用 context.Context 代替此参数的变体，
+// 变体的名字加上 Ctx 后缀，比如为 File.LoadContents 生成 File.LoadContentsCtx。
+// 发起异步操作后立即返回的函数不生成变体，因为变体返回时就不再监视 ctx 了。
+func pContextVariants(s *SourceFile, fnCtxs []*pFuncContext) {
+	fnNameMap := make(map[string]struct{}, len(fnCtxs))
+	for _, ctx := range fnCtxs {
+		fnNameMap[ctx.fnName] = struct{}{}
+	}
+
+	for _, ctx := range fnCtxs {
+		if ctx.asyncCbParam != "" || ctx.isOmitted() {
+			continue
+		}
+		cancellableIdx := getCancellableParamIdx(ctx)
+		if cancellableIdx == -1 {
+			continue
+		}
+		fnName := ctx.fnName + "Ctx"
+		if _, ok := fnNameMap[fnName]; ok {
+			s.GoBody.Pn("// ignore context variant %v, name conflicts\n", fnName)
+			continue
+		}
+		pContextVariant(s, fnName, ctx, cancellableIdx)
+	}
+}
+
+func pContextVariant(s *SourceFile, fnName string, ctx *pFuncContext, cancellableIdx int) {
+	var varReg VarReg
+	varV := varReg.alloc("v")
+
+	var params []string
+	var callArgs []string
+	var varCancellable string
+	for idx, param := range ctx.params {
+		parts := strings.SplitN(param, " ", 2)
+		paramName := varReg.alloc(parts[0])
+		callArgs = append(callArgs, paramName)
+		if idx == cancellableIdx {
+			varCancellable = paramName
+			continue
+		}
+		params = append(params, paramName+" "+parts[1])
+	}
+	for _, retParam := range ctx.retParams {
+		varReg.alloc(strings.SplitN(retParam, " ", 2)[0])
+	}
+	varCtx := varReg.alloc("ctx")
+	varRelease := varReg.alloc("release")
+	params = append([]string{varCtx + " context.Context"}, params...)
+
+	retPart := strings.Join(ctx.retParams, ", ")
+	if len(ctx.retParams) > 0 {
+		retPart = "(" + retPart + ")"
+	}
+
+	callPrefix := ""
+	if ctx.receiver != "" {
+		callPrefix = varV + "."
+	}
+	call := callPrefix + ctx.fnName + "(" + strings.Join(callArgs, ", ") + ")"
+	if len(ctx.retParams) > 0 {
+		call = "return " + call
+	}
+
+	s.AddGoImport("context")
+	s.GoBody.Pn("// %v 和 %v 一样，只是用 %v 代替了 %v 参数，%v 结束时操作会被取消。",
+		fnName, ctx.fnName, varCtx, varCancellable, varCtx)
+	s.GoBody.Pn("func %v %v(%v) %v {", ctx.receiver, fnName, strings.Join(params, ", "), retPart)
+	s.GoBody.Pn("%v, %v := %vCancellableFromContext(%v)", varCancellable, varRelease, getPkgPrefix("Gio"), varCtx)
+	s.GoBody.Pn("defer %v()", varRelease)
+	s.GoBody.Pn(call)
+	s.GoBody.Pn("}")
+}
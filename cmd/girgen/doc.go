@@ -0,0 +1,270 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/electricface/go-gir3/cmd/girgen/xmlp"
+)
+
+// docConverter 把 gtk-doc 标记转换为 Go 的标识符
+type docConverter struct {
+	// 键是 C 标识符的前缀，比如 Gtk，值是对应的 Go 包前缀，比如 "gtk."，当前命名空间的为 ""
+	cPrefixes map[string]string
+	// 键是命名空间，比如 Gtk，值是对应的 Go 包前缀
+	nsPrefixes map[string]string
+	// 键是枚举成员在 C 中的名字，比如 GTK_ORIENTATION_VERTICAL，值是对应的 Go 常量名，比如 OrientationVertical
+	constants map[string]string
+}
+
+var _docConverter *docConverter
+
+var (
+	// 比如 #GtkWidget、#GtkWidget:visible、#GtkWidget::destroy
+	_docTypeRegexp = regexp.MustCompile(`#([A-Z][A-Za-z0-9_]*)((?:::?[a-z][a-z0-9_-]*)?)`)
+	// 比如 %TRUE、%GTK_ORIENTATION_VERTICAL
+	_docConstRegexp = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_]*)`)
+	// 比如 @widget
+	_docParamRegexp = regexp.MustCompile(`(^|[^\w])@([A-Za-z_][A-Za-z0-9_]*)`)
+	// gi-docgen 的链接，比如 [class@Gtk.Widget]、[method@Gtk.Widget.show]
+	_docLinkRegexp = regexp.MustCompile(`\[([a-z_]+)@([A-Za-z0-9_.:-]+)\]`)
+)
+
+// getDocConverter 根据当前命名空间和它依赖的命名空间创建 docConverter
+func getDocConverter() *docConverter {
+	if _docConverter != nil {
+		return _docConverter
+	}
+	dc := &docConverter{
+		cPrefixes:  make(map[string]string),
+		nsPrefixes: make(map[string]string),
+		constants:  make(map[string]string),
+	}
+	visited := make(map[*xmlp.Repository]struct{})
+	var walk func(repo *xmlp.Repository)
+	walk = func(repo *xmlp.Repository) {
+		if _, ok := visited[repo]; ok {
+			return
+		}
+		visited[repo] = struct{}{}
+		ns := repo.Namespace.Name
+		pkgPrefix := ""
+		if !isSameNamespace(ns) {
+			pkgPrefix = getDocPkgPrefix(ns)
+		}
+		dc.nsPrefixes[ns] = pkgPrefix
+		for _, cPrefix := range strings.Split(repo.Namespace.CIdentifierPrefixes, ",") {
+			if cPrefix != "" {
+				dc.cPrefixes[cPrefix] = pkgPrefix
+			}
+		}
+		if pkgPrefix == "" {
+			enums := append(repo.Namespace.Enums, repo.Namespace.Bitfields...)
+			for _, enum := range enums {
+				for _, member := range enum.Members {
+					if member.CIdentifier != "" {
+						dc.constants[member.CIdentifier] = getTypeName(enum.NameAttr) + snake2Camel(member.Name)
+					}
+				}
+			}
+		}
+		for _, includeRepo := range repo.IncludeRepos() {
+			walk(includeRepo)
+		}
+	}
+	walk(_xRepo)
+	_docConverter = dc
+	return dc
+}
+
+// getDocPkgPrefix 返回命名空间 ns 对应的 Go 包前缀，和 getPkgPrefix 不同，它不会导入包，只用于注释。
+func getDocPkgPrefix(ns string) string {
+	return getGoPackageName(ns) + "."
+}
+
+// convertType 把 C 类型名转换为 Go 类型名，比如把 GtkWidget 转换为 gtk.Widget，不认识的保持原样。
+func (dc *docConverter) convertType(cType string) string {
+	bestPrefix := ""
+	for cPrefix := range dc.cPrefixes {
+		// 前缀之后必须是大写字母，比如 GtkWidget 而不是 G_TYPE_INT
+		if len(cPrefix) > len(bestPrefix) && strings.HasPrefix(cType, cPrefix) &&
+			len(cType) > len(cPrefix) && unicode.IsUpper(rune(cType[len(cPrefix)])) {
+			bestPrefix = cPrefix
+		}
+	}
+	if bestPrefix == "" {
+		return cType
+	}
+	return dc.cPrefixes[bestPrefix] + applyInitialisms(cType[len(bestPrefix):])
+}
+
+// convertLink 转换 gi-docgen 的链接，比如把 [method@Gtk.Widget.show] 转换为 gtk.Widget.Show。
+func (dc *docConverter) convertLink(kind, target string) string {
+	parts := strings.Split(target, ".")
+	pkgPrefix, ok := dc.nsPrefixes[parts[0]]
+	if ok && len(parts) > 1 {
+		parts = parts[1:]
+	} else {
+		pkgPrefix = ""
+	}
+	switch kind {
+	case "method", "func", "ctor", "vfunc":
+		last := len(parts) - 1
+		parts[last] = snake2Camel(parts[last])
+	}
+	return pkgPrefix + strings.Join(parts, ".")
+}
+
+// convertLine 转换一行中的 gtk-doc 标记
+func (dc *docConverter) convertLine(line string) string {
+	line = _docLinkRegexp.ReplaceAllStringFunc(line, func(s string) string {
+		m := _docLinkRegexp.FindStringSubmatch(s)
+		return dc.convertLink(m[1], m[2])
+	})
+	line = _docTypeRegexp.ReplaceAllStringFunc(line, func(s string) string {
+		m := _docTypeRegexp.FindStringSubmatch(s)
+		return dc.convertType(m[1]) + m[2]
+	})
+	line = _docConstRegexp.ReplaceAllStringFunc(line, func(s string) string {
+		name := s[1:]
+		switch name {
+		case "TRUE":
+			return "true"
+		case "FALSE":
+			return "false"
+		case "NULL":
+			return "nil"
+		}
+		if goName, ok := dc.constants[name]; ok {
+			return goName
+		}
+		return name
+	})
+	line = _docParamRegexp.ReplaceAllString(line, "$1$2")
+	return line
+}
+
+// convert 把 gtk-doc 格式的文档转换为 godoc 注释的各行，不含 "// " 前缀。
+// 代码块（|[ ... ]| 或 ``` ... ```）中的内容不做转换，只缩进。
+func (dc *docConverter) convert(text string) []string {
+	var result []string
+	inCode := false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t")
+		trimmed := strings.TrimSpace(line)
+		if !inCode && (strings.HasPrefix(trimmed, "|[") || strings.HasPrefix(trimmed, "```")) {
+			inCode = true
+			continue
+		}
+		if inCode {
+			if strings.HasPrefix(trimmed, "]|") || strings.HasPrefix(trimmed, "```") {
+				inCode = false
+				continue
+			}
+			if trimmed == "" {
+				result = append(result, "")
+			} else {
+				result = append(result, "\t"+line)
+			}
+			continue
+		}
+		result = append(result, dc.convertLine(line))
+	}
+	return result
+}
+
+// getDocLines 把 GIR 中的文档信息转换为 godoc 注释的各行，不含 "// " 前缀，没有文档时返回 nil。
+// 版本信息和弃用信息作为单独的段落放在最后。
+func getDocLines(doc *xmlp.Documentation) []string {
+	if doc == nil {
+		return nil
+	}
+	dc := getDocConverter()
+	var lines []string
+	if doc.Doc != nil && strings.TrimSpace(doc.Doc.Text) != "" {
+		lines = append(lines, dc.convert(doc.Doc.Text)...)
+	}
+	if doc.Version != "" {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "Since "+doc.Version)
+	}
+	if doc.Deprecated || doc.DocDeprecated != nil || doc.DeprecatedVersion != "" {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		deprecated := "Deprecated:"
+		if doc.DeprecatedVersion != "" {
+			deprecated += " since " + doc.DeprecatedVersion + "."
+		}
+		if doc.DocDeprecated != nil && strings.TrimSpace(doc.DocDeprecated.Text) != "" {
+			depLines := dc.convert(doc.DocDeprecated.Text)
+			deprecated += " " + depLines[0]
+			lines = append(lines, deprecated)
+			lines = append(lines, depLines[1:]...)
+		} else {
+			lines = append(lines, deprecated)
+		}
+	}
+	return lines
+}
+
+// pDoc 输出 GIR 中的文档信息作为注释，doc 可以为 nil。
+// 使用 WriteString 输出，避免文档中的内容被当作格式字符串或者 /*go:xxx*/ 这样的导入标记。
+func pDoc(b *SourceBody, doc *xmlp.Documentation) {
+	pDocLines(b, getDocLines(doc))
+}
+
+func pDocLines(b *SourceBody, lines []string) {
+	for _, line := range lines {
+		if line == "" {
+			b.WriteString("//\n")
+		} else {
+			b.WriteString("// " + line + "\n")
+		}
+	}
+}
+
+// pDocParagraph 在已有的注释（比如 "// Object Xxx"）之后输出 doc，中间用空注释行隔开。
+func pDocParagraph(b *SourceBody, doc *xmlp.Documentation) {
+	lines := getDocLines(doc)
+	if len(lines) == 0 {
+		return
+	}
+	b.WriteString("//\n")
+	pDocLines(b, lines)
+}
+
+// getTypeDoc 获取本命名空间中类型 name 的文档信息，找不到返回 nil。
+func getTypeDoc(name string) *xmlp.Documentation {
+	typeDef, _ := _xRepo.GetType(name)
+	if d, ok := typeDef.(interface {
+		GetDocumentation() *xmlp.Documentation
+	}); ok {
+		return d.GetDocumentation()
+	}
+	return nil
+}
@@ -22,8 +22,19 @@
 package main
 
 import (
+	"errors"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/electricface/go-gir3/gi"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -60,3 +71,675 @@ func Test_getC(t *testing.T) {
 		getConstructorName("DesktopAppInfo", "NewFromFilename"))
 	assert.Equal(t, "KeyFileCreateWithPath", getConstructorName("KeyFile", "CreateWithPath"))
 }
+
+func TestDocConverter(t *testing.T) {
+	dc := &docConverter{
+		cPrefixes:  map[string]string{"Gtk": "", "G": "g."},
+		nsPrefixes: map[string]string{"Gtk": "", "GObject": "g."},
+		constants:  map[string]string{"GTK_ORIENTATION_VERTICAL": "OrientationVertical"},
+	}
+	assert.Equal(t, "Returns true if widget is a Widget, g.Object::notify",
+		dc.convertLine("Returns %TRUE if @widget is a #GtkWidget, #GObject::notify"))
+	assert.Equal(t, "OrientationVertical or nil, mail foo@bar.com",
+		dc.convertLine("%GTK_ORIENTATION_VERTICAL or %NULL, mail foo@bar.com"))
+	assert.Equal(t, "G_TYPE_INT", dc.convertLine("#G_TYPE_INT"))
+	assert.Equal(t, "see Widget.Show and g.Object",
+		dc.convertLine("see [method@Gtk.Widget.show] and [class@GObject.Object]"))
+
+	lines := dc.convert("Example:\n|[<!-- language=\"C\" -->\n  gtk_widget_show (@w);\n]|\nDone.")
+	assert.Equal(t, []string{"Example:", "\t  gtk_widget_show (@w);", "Done."}, lines)
+}
+
+func Test_compareVersion(t *testing.T) {
+	assert.Equal(t, 0, compareVersion("2.66", "2.66.0"))
+	assert.Equal(t, -1, compareVersion("2.6", "2.56"))
+	assert.Equal(t, 1, compareVersion("3.24.1", "3.24"))
+	assert.Equal(t, 1, compareVersion("3.0", "2.99"))
+}
+
+func Test_parseMetadata(t *testing.T) {
+	md, err := parseMetadata(strings.NewReader(`
+# comment
+Setting8021x.set_ca_cert.out_format direction=out transfer=full # comment
+get_data.return array-length=length element-type=Gio.File* nullable=true
+get_table.return key-type=utf8 value-type=Gio.File*
+`))
+	assert.Nil(t, err)
+	assert.Len(t, md.rules, 3)
+
+	rule := md.rules["Setting8021x.set_ca_cert.out_format"]
+	if assert.NotNil(t, rule) {
+		assert.Equal(t, 3, rule.lineNum)
+		assert.Equal(t, gi.DIRECTION_OUT, *rule.override.Direction)
+		assert.Equal(t, gi.TRANSFER_EVERYTHING, *rule.override.Transfer)
+		assert.Nil(t, rule.override.Nullable)
+	}
+
+	rule = md.rules["get_data.return"]
+	if assert.NotNil(t, rule) {
+		assert.Equal(t, "length", rule.arrayLength)
+		assert.True(t, *rule.override.Nullable)
+		assert.Equal(t, &gi.ElemType{Tag: gi.TYPE_TAG_INTERFACE, IsPointer: true,
+			Namespace: "Gio", Name: "File"}, rule.override.ElemType)
+	}
+
+	rule = md.rules["get_table.return"]
+	if assert.NotNil(t, rule) {
+		assert.Nil(t, rule.override.ElemType)
+		assert.Equal(t, &gi.ElemType{Tag: gi.TYPE_TAG_UTF8, IsPointer: true}, rule.override.KeyType)
+		assert.Equal(t, &gi.ElemType{Tag: gi.TYPE_TAG_INTERFACE, IsPointer: true,
+			Namespace: "Gio", Name: "File"}, rule.override.ValueType)
+	}
+
+	_, err = parseMetadata(strings.NewReader("foo.bar scope=never"))
+	assert.NotNil(t, err)
+	_, err = parseMetadata(strings.NewReader("foo.bar nullable=true\nfoo.bar transfer=none"))
+	assert.NotNil(t, err)
+	_, err = parseMetadata(strings.NewReader("foo transfer=none"))
+	assert.NotNil(t, err)
+	_, err = parseMetadata(strings.NewReader("Widget.draw#method.cr nullable=true"))
+	assert.NotNil(t, err)
+	_, err = parseMetadata(strings.NewReader("Widget.draw#vfunc nullable=true"))
+	assert.NotNil(t, err)
+}
+
+func Test_metadataFindRule(t *testing.T) {
+	md, err := parseMetadata(strings.NewReader(`
+Widget.draw.cr nullable=true
+Widget.draw#vfunc.cr nullable=false
+Widget.show.return transfer=none
+Widget.hide#signal.return transfer=none
+Widget.unused.return transfer=none
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// 带种类的选择器优先，不同种类的可调用对象不会用上别的种类的规则
+	assert.Equal(t, "Widget.draw#vfunc.cr", md.findRule("vfunc", "Widget.draw", "cr").selector)
+	assert.Equal(t, "Widget.draw.cr", md.findRule("function", "Widget.draw", "cr").selector)
+	assert.Nil(t, md.findRule("function", "Widget.hide", "return"))
+	assert.Equal(t, "Widget.hide#signal.return", md.findRule("signal", "Widget.hide", "return").selector)
+
+	// 没有种类的选择器被方法和信号都用上了，有歧义
+	md.findRule("function", "Widget.show", "return")
+	md.findRule("signal", "Widget.show", "return")
+	assert.Equal(t, []string{
+		`metadata line 4: selector "Widget.show.return" is ambiguous, it matches function, signal, add #kind after the name`,
+		`metadata line 6: selector "Widget.unused.return" is never used`,
+	}, md.getWarnings())
+}
+
+func Test_applyInitialisms(t *testing.T) {
+	initNaming(&config{Initialisms: []string{"URI", "ID", "DBus", "HTTP"}})
+	defer initNaming(&config{})
+
+	assert.Equal(t, "NewURIFromString", applyInitialisms("NewUriFromString"))
+	assert.Equal(t, "GetID", snake2Camel("get_id"))
+	assert.Equal(t, "Identity", snake2Camel("identity"))
+	assert.Equal(t, "DBusProxyNewForBus", snake2Camel("dbus_proxy_new_for_bus"))
+	assert.Equal(t, "HTTPURI2", applyInitialisms("HttpUri2"))
+	assert.Equal(t, "SigNotifyID", "Sig"+toCamelCase("notify-id", "-"))
+	// 单词的左边界
+	assert.Equal(t, "TId", applyInitialisms("TId"))
+	assert.Equal(t, "XMLID", applyInitialisms("XMLId"))
+	assert.Equal(t, "Get2ID", applyInitialisms("Get2Id"))
+}
+
+func Test_getDeniedFuncsRenameWarnings(t *testing.T) {
+	defer func() {
+		_funcRenames = make(map[string]string)
+		_matchedConfigEntries = make(map[configEntry]struct{})
+	}()
+	cfg := &config{DeniedFuncs: []string{"GetUri", "GetID", "Foo"}}
+	recordFuncRename(nil, "GetUri", "GetURI")
+	recordFuncRename(nil, "GetId", "GetID")
+	recordFuncRename(nil, "Foo", "Foo")
+	assert.True(t, cfg.isFuncDenied("GetID"))
+	assert.False(t, cfg.isFuncDenied("GetURI"))
+
+	assert.Equal(t, []string{
+		`DeniedFuncs entry "GetUri" matches nothing, since the function is renamed to "GetURI"`,
+	}, getDeniedFuncsRenameWarnings(cfg))
+}
+
+func Test_checkNameCollisions(t *testing.T) {
+	collisions, err := checkNameCollisions([]byte(`package g
+type Uri struct { P unsafe.Pointer; Parse int }
+func Uri() {}
+func (v Uri) Parse() {}
+func (v *Uri) String() {}
+func (v Uri) String() {}
+const _ = 1
+const _ = 2
+func init() {}
+func init() {}
+`), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"Uri declared at line 2 and line 3",
+		"Uri.Parse declared at line 2 and line 4",
+		"Uri.String declared at line 5 and line 6",
+	}, collisions)
+
+	collisions, err = checkNameCollisions([]byte(`package g
+type Object struct { P unsafe.Pointer }
+func (v Object) Ref() {}
+func IdleAdd() {}
+`), map[string][]byte{
+		"object.go": []byte(`package g
+func (v Object) Ref() {}
+func (v Object) Unref() {}
+func init() {}
+`),
+		"idle.go": []byte(`package g
+var IdleAdd = 1
+`),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"IdleAdd declared at line 4 and idle.go:2",
+		"Object.Ref declared at line 3 and object.go:2",
+	}, collisions)
+}
+
+func Test_loadConfig(t *testing.T) {
+	load := func(content string) (*config, error) {
+		f, err := ioutil.TempFile("", "girgen-config")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(content)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cfg config
+		err = loadConfig(f.Name(), &cfg)
+		return &cfg, err
+	}
+
+	cfg, err := load(`{"DeniedFuncs": ["IdleAdd", "Value.Init"], "DeniedFields": ["TestLogMsg.log_type"]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"IdleAdd", "Value.Init"}, cfg.DeniedFuncs)
+
+	_, err = load(`{"DeniedFunc": ["IdleAdd"]}`)
+	assert.NotNil(t, err)
+	_, err = load(`{"DeniedFuncs": ["IdleAdd", "IdleAdd"]}`)
+	assert.NotNil(t, err)
+	_, err = load(`{"DeniedFields": ["TestLogMsg"]}`)
+	assert.NotNil(t, err)
+	_, err = load(`{"MinVersion": "2.x"}`)
+	assert.NotNil(t, err)
+	_, err = load(`{"Renames": {"g_uri_parse": "Parse URI"}}`)
+	assert.NotNil(t, err)
+	cfg, err = load(`{"Unsupported": "stub"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, unsupportedStub, cfg.Unsupported)
+	_, err = load(`{"Unsupported": "skip"}`)
+	assert.NotNil(t, err)
+}
+
+func Test_getUnmatchedEntries(t *testing.T) {
+	cfg := &config{
+		DeniedFuncs: []string{"IdleAdd", "AttrIterator.Destroy11"},
+		NoGetType:   []string{"VariantType"},
+	}
+	assert.True(t, cfg.isFuncDenied("IdleAdd"))
+	assert.False(t, cfg.isFuncDenied("TimeoutAdd"))
+	assert.True(t, cfg.isNoGetType("VariantType"))
+	assert.Equal(t, []configEntry{{key: "DeniedFuncs", item: "AttrIterator.Destroy11"}},
+		cfg.getUnmatchedEntries())
+}
+
+func getTestBuildJobs(t *testing.T, deps map[string][]string) []*buildJob {
+	m := &manifest{
+		Packages: []*manifestPackage{
+			{Package: "g", Dir: "g-2.0", Namespaces: []*manifestNamespace{
+				{Name: "GLib", Version: "2.0"},
+				{Name: "GObject", Version: "2.0"},
+				{Name: "Gio", Version: "2.0"},
+			}},
+			{Namespaces: []*manifestNamespace{{Name: "Atk", Version: "1.0"}}},
+			{Namespaces: []*manifestNamespace{{Name: "Gtk", Version: "3.0"}}},
+		},
+	}
+	assert.Nil(t, m.normalize())
+	assert.Equal(t, "gtk", m.Packages[2].Package)
+	assert.Equal(t, "gtk-3.0", m.Packages[2].Dir)
+	assert.Equal(t, "gtk_auto.go", m.Packages[2].Namespaces[0].File)
+	return getBuildJobs(m, func(ns *manifestNamespace) []string {
+		return deps[ns.nameVersion()]
+	})
+}
+
+func Test_sortBuildJobs(t *testing.T) {
+	jobs := getTestBuildJobs(t, map[string][]string{
+		"Gtk-3.0":     {"Atk-1.0", "Gio-2.0", "GObject-2.0", "GLib-2.0"},
+		"Atk-1.0":     {"GObject-2.0", "GLib-2.0"},
+		"GObject-2.0": {"GLib-2.0"},
+		// Gio 依赖 GObject，但是没有依赖 GLib 的也要在 GLib 之后
+		"Gio-2.0": {"GObject-2.0"},
+	})
+	jobs, err := sortBuildJobs(jobs)
+	assert.Nil(t, err)
+	var names []string
+	for _, job := range jobs {
+		names = append(names, job.String())
+	}
+	assert.Equal(t, []string{"GLib-2.0", "GObject-2.0", "Gio-2.0", "Atk-1.0", "Gtk-3.0"}, names)
+
+	jobs = getTestBuildJobs(t, map[string][]string{
+		"Atk-1.0": {"Gtk-3.0"},
+		"Gtk-3.0": {"Atk-1.0"},
+	})
+	_, err = sortBuildJobs(jobs)
+	assert.NotNil(t, err)
+}
+
+func Test_runBuildJobs(t *testing.T) {
+	jobs := getTestBuildJobs(t, map[string][]string{
+		"Gtk-3.0":     {"Atk-1.0", "Gio-2.0"},
+		"Atk-1.0":     {"GObject-2.0"},
+		"GObject-2.0": {"GLib-2.0"},
+	})
+	jobs, err := sortBuildJobs(jobs)
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	done := make(map[*buildJob]bool)
+	err = runBuildJobs(jobs, 4, func(job *buildJob) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dep := range job.deps {
+			assert.True(t, done[dep], "%v runs before %v", job, dep)
+		}
+		done[job] = true
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, len(jobs), len(done))
+
+	// 失败的任务之后的任务不会运行
+	var ran []string
+	err = runBuildJobs(jobs, 1, func(job *buildJob) error {
+		ran = append(ran, job.String())
+		if job.String() == "GObject-2.0" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"GLib-2.0", "GObject-2.0"}, ran)
+}
+
+func Test_pathListFlag(t *testing.T) {
+	var f pathListFlag
+	assert.Nil(t, f.Set("/opt/foo/share/gir-1.0"))
+	assert.Nil(t, f.Set("/opt/a"+string(os.PathListSeparator)+string(os.PathListSeparator)+"/opt/b"))
+	assert.Equal(t, pathListFlag{"/opt/foo/share/gir-1.0", "/opt/a", "/opt/b"}, f)
+}
+
+func Test_getGoPackage(t *testing.T) {
+	oldCfg, oldNs, oldVer, oldDeps := _cfg, _optNamespace, _optVersion, _deps
+	defer func() {
+		_cfg, _optNamespace, _optVersion, _deps = oldCfg, oldNs, oldVer, oldDeps
+	}()
+	_cfg = &config{Packages: map[string]*goPackage{
+		"Gtk":   {Path: "example.com/foo/gtk3"},
+		"Atk":   {Path: "example.com/foo/atk-1.0", Name: "atk"},
+		"MyLib": {Path: "example.com/foo/mylib"},
+	}}
+	_optNamespace, _optVersion = "MyLib", "1.0"
+	_deps = []string{"Atk-1.0", "GLib-2.0", "GObject-2.0", "Gdk-3.0", "Gtk-3.0"}
+
+	assert.Equal(t, "gtk,example.com/foo/gtk3", getGoPackage("Gtk").importExpr())
+	assert.Equal(t, "example.com/foo/atk-1.0", getGoPackage("Atk").importExpr())
+	assert.Equal(t, _girPkgPath+"/gdk-3.0", getGoPackage("Gdk").importExpr())
+	assert.Equal(t, goPackage{Path: _girPkgPath + "/g-2.0", Name: "g"}, getGoPackage("GObject"))
+	assert.Equal(t, goPackage{Name: "pango"}, getGoPackage("Pango"))
+
+	assert.True(t, isSameNamespace("MyLib"))
+	assert.False(t, isSameNamespace("GLib"))
+	_optNamespace, _optVersion = "Gio", "2.0"
+	assert.True(t, isSameNamespace("GLib"))
+	assert.True(t, isSameNamespace("GObject"))
+}
+
+func Test_updateGoMod(t *testing.T) {
+	content := updateGoMod(nil, "example.com/foo/gir")
+	assert.Equal(t, "module example.com/foo/gir\n\ngo 1.13\n", string(content))
+	assert.Equal(t, "example.com/foo/gir", getGoModModulePath(content))
+
+	content = []byte("// comment\nmodule \"example.com/old\"\n\ngo 1.16\n\nrequire golang.org/x/xerrors v0.0.0\n")
+	assert.Equal(t, "example.com/old", getGoModModulePath(content))
+	assert.Equal(t, "// comment\nmodule example.com/new\n\ngo 1.16\n\nrequire golang.org/x/xerrors v0.0.0\n",
+		string(updateGoMod(content, "example.com/new")))
+	assert.Equal(t, string(content), string(updateGoMod(content, "example.com/old")))
+}
+
+func Test_getTodoDetails(t *testing.T) {
+	code := "func F(a int/*TODO_TYPE isPtr: true, tag: ghash*/) {\n" +
+		"arg_a := gi.NewIntArgument(a)/*TODO*/\n" +
+		"b := int(v.p().b) /* TODO */\n" +
+		"c := int/*TODO_TYPE isPtr: true, tag: ghash*/\n}"
+	assert.Equal(t, []string{"TODO_TYPE isPtr: true, tag: ghash", "TODO"}, getTodoDetails(code))
+	assert.Nil(t, getTodoDetails("func F() {}"))
+}
+
+func Test_addTodoItem(t *testing.T) {
+	oldReport, oldMap := _todoReport, _todoItemMap
+	defer func() {
+		_todoReport, _todoItemMap = oldReport, oldMap
+	}()
+	_todoReport = &todoReport{}
+	_todoItemMap = make(map[string]*todoItem)
+
+	reason := todoReason{Arg: "x", Tag: "ghash", IsPointer: true}
+	addTodoItem(&todoItem{Kind: "field", Name: "Rect.x", Reasons: []todoReason{reason}})
+	addTodoItem(&todoItem{Kind: "field", Name: "Rect.x", Reasons: []todoReason{reason}, Details: []string{"TODO"}})
+	addTodoItem(&todoItem{Kind: "function", Name: "Rect.x"})
+	assert.Len(t, _todoReport.Items, 2)
+	assert.Equal(t, &todoItem{Kind: "field", Name: "Rect.x", Reasons: []todoReason{reason},
+		Details: []string{"TODO"}}, _todoReport.Items[0])
+}
+
+func Test_getHashTableToMapLines(t *testing.T) {
+	strElem := &ptrElemType{type0: "string", fromPtrFmt: "gi.GoString(%v)", isStr: true}
+	objElem := &ptrElemType{type0: "Object", fromPtrFmt: "Object{P: %v}", isRef: true}
+
+	var varReg VarReg
+	lines, ok := getHashTableToMapLines("result", "ret.Pointer()", strElem, strElem,
+		gi.TRANSFER_EVERYTHING, &varReg)
+	assert.True(t, ok)
+	assert.Equal(t, "gi.HashTableUnref(ret.Pointer())", lines[len(lines)-1])
+
+	_, ok = getHashTableToMapLines("result", "ret.Pointer()", strElem, objElem,
+		gi.TRANSFER_NOTHING, &varReg)
+	assert.True(t, ok)
+	_, ok = getHashTableToMapLines("result", "ret.Pointer()", strElem, objElem,
+		gi.TRANSFER_EVERYTHING, &varReg)
+	assert.False(t, ok)
+	_, ok = getHashTableToMapLines("result", "ret.Pointer()", objElem, strElem,
+		gi.TRANSFER_CONTAINER, &varReg)
+	assert.False(t, ok)
+}
+
+// parseVFuncOverride 解析 vfuncOverride.lines 返回的语句，返回传给 overrideFn 的 closure 的语句
+func parseVFuncOverride(t *testing.T, o *vfuncOverride) []ast.Stmt {
+	src := "package p\nfunc f() {\n" + strings.Join(o.lines(), "\n") + "\n}\n"
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if !assert.Nil(t, err) {
+		return nil
+	}
+	body := file.Decls[0].(*ast.FuncDecl).Body.List
+	ifStmt := body[0].(*ast.IfStmt)
+	call := ifStmt.Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr)
+	assert.Equal(t, o.overrideFn, exprString(call.Fun))
+	assert.Equal(t, o.varClass, exprString(call.Args[0]))
+	return call.Args[4].(*ast.FuncLit).Body.List
+}
+
+func exprString(expr ast.Expr) string {
+	var buf strings.Builder
+	_ = format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+func Test_vfuncOverrideLines(t *testing.T) {
+	o := &vfuncOverride{
+		overrideFn:      "gi.OverrideVFunc",
+		parentFn:        "gi.NewVFuncParent",
+		instanceDataFn:  "gi.GetClassInstanceData",
+		namespace:       "Gtk",
+		containerName:   "Widget",
+		vfName:          "draw",
+		ifcName:         "WidgetVFuncDraw",
+		methodName:      "VFuncDraw",
+		varClass:        "class",
+		varImpl:         "impl",
+		varOk:           "ok",
+		varResult:       "result",
+		varArgs:         "args",
+		varSelf:         "self",
+		varParent:       "parent1",
+		beforeCallLines: []string{"cr := cairo.Context{P: *(*unsafe.Pointer)(args[1])}"},
+		afterCallLines:  []string{"*(*int32)(result) = int32(gi.Bool2Int(fnRet))"},
+		fnArgs:          []string{"cr"},
+		fnRets:          []string{"fnRet"},
+	}
+	stmts := parseVFuncOverride(t, o)
+
+	var selfLookup, parentCall, methodCall *ast.CallExpr
+	var fallbackReturns bool
+	ast.Inspect(&ast.BlockStmt{List: stmts}, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if exprString(n.Lhs[0]) == "self" {
+				// self, ok := instanceDataFn(class, instance).(Ifc)
+				selfLookup = n.Rhs[0].(*ast.TypeAssertExpr).X.(*ast.CallExpr)
+				assert.Equal(t, o.ifcName, exprString(n.Rhs[0].(*ast.TypeAssertExpr).Type))
+			}
+			if call, ok := n.Rhs[0].(*ast.CallExpr); ok && exprString(call.Fun) == "self.VFuncDraw" {
+				methodCall = call
+			}
+		case *ast.IfStmt:
+			if exprString(n.Cond) == "!ok" {
+				parentCall = n.Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr)
+				_, fallbackReturns = n.Body.List[len(n.Body.List)-1].(*ast.ReturnStmt)
+			}
+		}
+		return true
+	})
+
+	// 用填充虚函数的类结构体找 Go 值，而不是实例的最后代类型的 Go 值，
+	// 否则在两层 Go 类型中调用 parent.Call() 会回到同一个 VFuncXxx 方法。
+	if assert.NotNil(t, selfLookup) {
+		assert.Equal(t, "gi.GetClassInstanceData", exprString(selfLookup.Fun))
+		assert.Equal(t, []string{"class", "*(*unsafe.Pointer)(args[0])"},
+			[]string{exprString(selfLookup.Args[0]), exprString(selfLookup.Args[1])})
+	}
+	// Go 值没有实现 VFuncXxx 方法时调用父类型的实现并返回
+	if assert.NotNil(t, parentCall) {
+		assert.Equal(t, "parent1.Call", exprString(parentCall.Fun))
+		assert.True(t, fallbackReturns)
+	}
+	// parent 是第一个参数，其余参数按顺序传入
+	if assert.NotNil(t, methodCall) {
+		var args []string
+		for _, arg := range methodCall.Args {
+			args = append(args, exprString(arg))
+		}
+		assert.Equal(t, []string{"parent1", "cr"}, args)
+	}
+
+	// 接口虚函数用接口结构体找 Go 值
+	o.overrideFn = "gi.OverrideInterfaceVFunc"
+	o.instanceDataFn = "gi.GetInterfaceInstanceData"
+	o.varClass = "iface"
+	stmts = parseVFuncOverride(t, o)
+	lookup := stmts[1].(*ast.AssignStmt).Rhs[0].(*ast.TypeAssertExpr).X.(*ast.CallExpr)
+	assert.Equal(t, "gi.GetInterfaceInstanceData(iface, *(*unsafe.Pointer)(args[0]))", exprString(lookup))
+}
+
+func Test_removeTodoParams(t *testing.T) {
+	params := []string{"name string", "table int/*TODO_TYPE isPtr: true, tag: ghash*/", "flags uint32"}
+	assert.Equal(t, []string{"name string", "flags uint32"}, removeTodoParams(params))
+	assert.Nil(t, removeTodoParams([]string{"result int/*TODO_TYPE*/"}))
+}
+
+func Test_loadDepNaming(t *testing.T) {
+	oldNs, oldDepNaming := _optNamespace, _depNaming
+	defer func() {
+		_optNamespace, _depNaming = oldNs, oldDepNaming
+		initNaming(&config{})
+	}()
+	_optNamespace = "Gtk"
+	_depNaming = make(map[string]*namingRules)
+	initNaming(&config{Initialisms: []string{"ID"}, Renames: map[string]string{"Widget": "Widget0"}})
+
+	dir, err := ioutil.TempDir("", "girgen-naming")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "GLib.json"),
+		[]byte(`{"Initialisms": ["URI"], "Renames": {"Variant": "GVariant"}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = loadDepNaming([]string{"GLib-2.0", "Gdk-3.0", "Gtk-3.0"}, func(ns, version string) string {
+		return filepath.Join(dir, ns+".json")
+	})
+	assert.Nil(t, err)
+	// GLib 的类型使用 GLib 配置中的规则
+	assert.Equal(t, "URIParamsFlags", getGoTypeName("GLib", "UriParamsFlags"))
+	assert.Equal(t, "GVariant", getGoTypeName("GLib", "Variant"))
+	// Gdk 没有配置文件，也不使用当前命名空间的首字母缩略词
+	assert.Equal(t, "DeviceId", getGoTypeName("Gdk", "DeviceId"))
+	assert.Equal(t, "Widget", getGoTypeName("Gdk", "Widget"))
+	// 当前命名空间
+	assert.Equal(t, "Widget0", getGoTypeName("Gtk", "Widget"))
+	assert.Equal(t, "UriID", getGoTypeName("Gtk", "UriId"))
+}
+
+func Test_getNamespaceConfigFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "girgen-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	err = os.MkdirAll(filepath.Join(root, "g-2.0"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(root, "g-2.0", "glib-config.json"), []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, filepath.Join(root, "g-2.0", "glib-config.json"),
+		getNamespaceConfigFile(nil, root, "GLib", "2.0"))
+	assert.Equal(t, filepath.Join(root, "g-2.0", "config.json"),
+		getNamespaceConfigFile(nil, root, "Gio", "2.0"))
+	assert.Equal(t, filepath.Join(root, "gtk-3.0", "config.json"),
+		getNamespaceConfigFile(nil, root, "Gtk", "3.0"))
+
+	m := &manifest{Packages: []*manifestPackage{{
+		Package: "gtk3",
+		Dir:     "gtk3",
+		Namespaces: []*manifestNamespace{
+			{Name: "Gtk", Version: "3.0", Config: "gtk-config.json"},
+		},
+	}}}
+	assert.Equal(t, filepath.Join(root, "gtk3", "gtk-config.json"),
+		getNamespaceConfigFile(m, root, "Gtk", "3.0"))
+}
+
+func Test_syncFilesWithModulePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "girgen-sync")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	oldGirPkgPath := _girPkgPath
+	_girPkgPath = "example.com/mygir"
+	defer func() {
+		_girPkgPath = oldGirPkgPath
+	}()
+
+	libInDir := filepath.Join(dir, "lib.in", "g-2.0")
+	outDir := filepath.Join(dir, "out", "g-2.0")
+	assert.Nil(t, os.MkdirAll(libInDir, 0755))
+	assert.Nil(t, os.MkdirAll(outDir, 0755))
+	src := `package g
+
+import (
+	"errors"
+	// gi 包
+	gi "github.com/electricface/go-gir/gi"
+	"github.com/electricface/go-gir/gtk-3.0"
+	"github.com/electricface/go-girx/foo"
+)
+
+var _ = errors.New
+`
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(libInDir, "value.go"), []byte(src), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(libInDir, "config.json"), []byte(`{"github.com/electricface/go-gir/gi":1}`), 0644))
+
+	assert.Nil(t, syncFilesToOut(libInDir, outDir))
+	content, err := ioutil.ReadFile(filepath.Join(outDir, "value.go"))
+	assert.Nil(t, err)
+	assert.Equal(t, strings.NewReplacer(
+		`"github.com/electricface/go-gir/gi"`, `"example.com/mygir/gi"`,
+		`"github.com/electricface/go-gir/gtk-3.0"`, `"example.com/mygir/gtk-3.0"`,
+	).Replace(src), string(content))
+	// 不是 .go 文件的原样复制
+	content, err = ioutil.ReadFile(filepath.Join(outDir, "config.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"github.com/electricface/go-gir/gi":1}`, string(content))
+
+	// 同步回 lib.in 时恢复默认的导入路径
+	assert.Nil(t, syncFilesToLibIn(libInDir, outDir))
+	content, err = ioutil.ReadFile(filepath.Join(libInDir, "value.go"))
+	assert.Nil(t, err)
+	assert.Equal(t, src, string(content))
+}
+
+func Test_findMethodConflicts(t *testing.T) {
+	assert.Equal(t, "func(ICellRenderer, bool)()", getSigType("PackStart(cell ICellRenderer, expand bool) "))
+	assert.True(t, isSameSigType("GetName() (result string)", "GetName() (name string)"))
+	assert.False(t, isSameSigType("PackStart(cell ICellRenderer, expand bool) ",
+		"PackStart(renderer ICellRenderer, expand bool, align bool, fixed bool) "))
+
+	// CellAreaBox 的父类型 CellArea 嵌入了 CellLayoutIfc，CellAreaBox.PackStart 遮盖了 CellLayoutIfc.PackStart。
+	ifcSigs := map[string]string{
+		"PackStart": "PackStart(cell ICellRenderer, expand bool) ",
+		"Clear":     "Clear() ",
+		"Reorder":   "Reorder(cell ICellRenderer, position int32) ",
+	}
+	levels := [][]methodOwner{
+		{{name: "CellAreaBox", sigs: map[string]string{
+			"PackStart": "PackStart(renderer ICellRenderer, expand bool, align bool, fixed bool) ",
+			"Clear":     "Clear() ",
+		}}},
+		{{name: "CellArea", sigs: map[string]string{"Add": "Add(renderer ICellRenderer) "}}},
+		{{name: "InitiallyUnowned"}, {name: "BuildableIfc", sigs: map[string]string{
+			"Reorder": "Reorder(child IObject, position int32) ",
+		}}},
+	}
+	// 同签名的 Clear 不影响，深度相同的 Reorder 冲突
+	assert.Equal(t, []string{"CellAreaBox.PackStart", "BuildableIfc.Reorder"},
+		findMethodConflicts(ifcSigs, levels, 2))
+	assert.Equal(t, []string{"CellAreaBox.PackStart"}, findMethodConflicts(ifcSigs, levels[:2], 1))
+	assert.Nil(t, findMethodConflicts(map[string]string{"Add": "Add(renderer ICellRenderer) "}, levels, 2))
+}
+
+func Test_getSliceToListLines(t *testing.T) {
+	strElem := &ptrElemType{type0: "string", toPtrFmt: "unsafe.Pointer(gi.CString(%v))", isStr: true}
+	objElem := &ptrElemType{type0: "Object", toPtrFmt: "%v.P", isRef: true}
+
+	var varReg VarReg
+	// 被调用者只拥有列表本身，调用后要释放字符串元素，但不能再访问列表。
+	_, afterCallLines, ok := getSliceToListLines("names", "list", gi.TYPE_TAG_GLIST, strElem,
+		gi.TRANSFER_CONTAINER, &varReg)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"for _, item := range items_names {", "gi.Free(item)", "}"}, afterCallLines)
+
+	_, afterCallLines, ok = getSliceToListLines("objs", "list1", gi.TYPE_TAG_GSLIST, objElem,
+		gi.TRANSFER_CONTAINER, &varReg)
+	assert.True(t, ok)
+	assert.Empty(t, afterCallLines)
+
+	varReg = VarReg{}
+	_, afterCallLines, ok = getSliceToListLines("names", "list", gi.TYPE_TAG_GLIST, strElem,
+		gi.TRANSFER_NOTHING, &varReg)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"gi.ListFreeFull(list)"}, afterCallLines)
+
+	_, _, ok = getSliceToListLines("objs", "list", gi.TYPE_TAG_GLIST, objElem, gi.TRANSFER_EVERYTHING, &varReg)
+	assert.False(t, ok)
+}
@@ -25,6 +25,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
@@ -36,7 +37,10 @@ import (
 	"github.com/electricface/go-gir3/gi"
 )
 
-var _girPkgPath = "github.com/electricface/go-gir"
+var _girPkgPath = libInGirPkgPath
+
+// lib.in 中手写的文件导入生成的包和 gi 包时使用的路径前缀，同步文件时改为 _girPkgPath。
+const libInGirPkgPath = "github.com/electricface/go-gir"
 
 const fileHeader = `/*
  * Copyright (C) 2019 ~ $year Uniontech Software Technology Co.,Ltd
@@ -70,6 +74,13 @@ var _optOutputFile string
 var _optCfgFile string
 var _optPkg string
 var _optSyncGi bool
+var _optMinVersion string
+var _optUnsupported string
+var _optReportFile string
+var _optReportHtmlFile string
+var _optGirDirs pathListFlag
+var _optTypelibDirs pathListFlag
+var _optManifestFile string
 
 var _xRepo *xmlp.Repository
 
@@ -82,9 +93,18 @@ func init() {
 	flag.StringVar(&_optCfgFile, "c", "", "config file")
 	flag.StringVar(&_optPkg, "p", "", "package")
 	flag.BoolVar(&_optSyncGi, "sync-gi", false, "sync gi to out dir")
+	flag.StringVar(&_optMinVersion, "min-version", "", "minimum library version to support, overrides MinVersion in config file")
+	flag.StringVar(&_optUnsupported, "unsupported", "", "how to handle unsupported functions: keep, omit or stub, overrides Unsupported in config file")
+	flag.StringVar(&_optReportFile, "report", "", "write a JSON report of functions, fields, callbacks and signals that produced TODO code to this file")
+	flag.StringVar(&_optReportHtmlFile, "report-html", "", "write an HTML version of the report to this file")
+	flag.StringVar(&_optModuleRoot, "module-root", "", "root directory of the output module, generate packages into it instead of GOPATH")
+	flag.StringVar(&_optModulePath, "module-path", "", "module path of the output module, defaults to the module path in go.mod or GIR_PKG_PATH")
+	flag.Var(&_optGirDirs, "gir-dir", "directory to search for .gir files, can be repeated or a list separated by "+string(os.PathListSeparator))
+	flag.StringVar(&_optManifestFile, "manifest", "", "manifest file, used to find the config files of dependent namespaces")
+	flag.Var(&_optTypelibDirs, "typelib-dir", "directory to search for .typelib files, can be repeated or a list separated by "+string(os.PathListSeparator))
 }
 
-var _structNamesMap = make(map[string]struct{}) // 键是所有 struct 类型名。
+var _structNamesMap = make(map[string]struct{}) // 键是所有 struct 类型在 Go 中的名字。
 var _symbolNameMap = make(map[string]string)    // 键是 c 符号， value 是方法名，是调整过的方法名。
 
 // 它是 getAllDeps() 的返回结果
@@ -98,6 +118,18 @@ var _sigNamesMap = make(map[string]struct{}) // 键是所有信号名
 func main() {
 	flag.Parse()
 
+	envGirPkgPath := os.Getenv("GIR_PKG_PATH")
+	if envGirPkgPath != "" {
+		_girPkgPath = envGirPkgPath
+	}
+
+	if isModuleMode() {
+		err := setupModule()
+		if err != nil {
+			log.Fatalf("failed to setup module: %v", err)
+		}
+	}
+
 	if _optSyncGi {
 		err := syncLibGiToOut()
 		if err != nil {
@@ -106,15 +138,18 @@ func main() {
 		return
 	}
 
-	envGirPkgPath := os.Getenv("GIR_PKG_PATH")
-	if envGirPkgPath != "" {
-		_girPkgPath = envGirPkgPath
+	if flag.Arg(0) == "build" {
+		err := runBuild(flag.Args()[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	gopath := getGoPath()
+	setupSearchPath()
+
 	if _optDir == "" {
-		_optDir = filepath.Join(gopath, "src", _girPkgPath,
-			strings.ToLower(_optNamespace+"-"+_optVersion))
+		_optDir = filepath.Join(getPkgRootDir(), strings.ToLower(_optNamespace+"-"+_optVersion))
 	}
 
 	pkg := strings.ToLower(_optNamespace)
@@ -124,7 +159,13 @@ func main() {
 
 	outFile := filepath.Join(_optDir, pkg+"_auto.go")
 	if _optOutputFile != "" {
-		outFile = strings.Replace(_optOutputFile, "%GOPATH%", gopath, 1)
+		outFile = _optOutputFile
+		if strings.Contains(outFile, "%GOPATH%") {
+			if isModuleMode() {
+				log.Fatalf("%v in output file is not supported in module mode", "%GOPATH%")
+			}
+			outFile = strings.Replace(outFile, "%GOPATH%", getGoPath(), 1)
+		}
 	}
 	log.Print("outFile:", outFile)
 
@@ -162,28 +203,6 @@ func main() {
 		log.Fatalf("invalid sync mode %q", envMode)
 	}
 
-	genStateFile := filepath.Join(outDir, "genState.json")
-	if _optNamespace == "GObject" || _optNamespace == "Gio" {
-		var state genState
-		err = loadGenState(genStateFile, &state)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		nsOrder := []string{"GLib", "GObject", "Gio"}
-		var prevNs string
-		for i, ns := range nsOrder {
-			if ns == _optNamespace {
-				prevNs = nsOrder[i-1]
-			}
-		}
-		if state.PrevNamespace != prevNs {
-			log.Fatalf("prev namespace is not %v", prevNs)
-		}
-
-		_funcNextIdx = state.FuncNextId
-		_getTypeNextId = state.GetTypeNextId
-	}
-
 	configFile := filepath.Join(outDir, "config.json")
 	if _optCfgFile != "" {
 		configFile = filepath.Join(outDir, _optCfgFile)
@@ -194,7 +213,29 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if _optMinVersion != "" {
+		if !isValidVersion(_optMinVersion) {
+			log.Fatalf("invalid min version %q", _optMinVersion)
+		}
+		cfg.MinVersion = _optMinVersion
+	}
+	if _optUnsupported != "" {
+		if !isValidUnsupportedMode(_optUnsupported) {
+			log.Fatalf("invalid unsupported mode %q", _optUnsupported)
+		}
+		cfg.Unsupported = _optUnsupported
+	}
 	_cfg = &cfg
+	initNaming(_cfg)
+
+	metadataFile := filepath.Join(outDir, _optNamespace+"-"+_optVersion+".metadata")
+	md, err := loadMetadata(metadataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if md != nil {
+		gi.SetOverrideFunc(md.getOverride)
+	}
 
 	repo := gi.DefaultRepository()
 	_, err = repo.Require(_optNamespace, _optVersion, gi.REPOSITORY_LOAD_FLAG_LAZY)
@@ -211,6 +252,20 @@ func main() {
 	log.Printf("deps: %#v\n", deps)
 	_deps = deps
 
+	var m *manifest
+	if _optManifestFile != "" {
+		m, err = loadManifest(_optManifestFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	err = loadDepNaming(deps, func(ns, version string) string {
+		return getNamespaceConfigFile(m, getPkgRootDir(), ns, version)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	sourceFile := NewSourceFile(pkg)
 	_sourceFile = sourceFile
 
@@ -264,21 +319,22 @@ func main() {
 		name := bi.Name()
 		switch bi.Type() {
 		case gi.INFO_TYPE_STRUCT, gi.INFO_TYPE_UNION, gi.INFO_TYPE_OBJECT, gi.INFO_TYPE_INTERFACE:
-			_structNamesMap[name] = struct{}{}
+			_structNamesMap[getTypeName(name)] = struct{}{}
 		}
 		bi.Unref()
 	}
 
 	// 处理函数命名冲突
 	forEachFunctionInfo(repo, _optNamespace, handleFuncNameClash)
-	var constants []string
+	var constants []constantDef
+	var fnCtxs []*pFuncContext
 
 	for idxLv1 := 0; idxLv1 < numInfos; idxLv1++ {
 		bi := repo.Info(_optNamespace, idxLv1)
 		switch bi.Type() {
 		case gi.INFO_TYPE_FUNCTION:
 			fi := gi.ToFunctionInfo(bi)
-			pFunction(sourceFile, fi, idxLv1, 0)
+			fnCtxs = append(fnCtxs, pFunction(sourceFile, fi, idxLv1, 0))
 
 		case gi.INFO_TYPE_CALLBACK:
 			ci := gi.ToCallableInfo(bi)
@@ -323,61 +379,25 @@ func main() {
 		bi.Unref()
 	}
 
+	fnCtxs = append(fnCtxs, pAsyncWrappers(sourceFile, fnCtxs, xRepo.Namespace.Functions)...)
+	pContextVariants(sourceFile, fnCtxs)
+	pMethodSetAssertions(sourceFile, repo)
+
 	// print constants
-	sourceFile.GoBody.Pn("// constants\nconst (")
-	for i := 0; i+1 < len(constants); i += 2 {
-		sourceFile.GoBody.Pn("%s = %s", constants[i], constants[i+1])
+	identMap := make(map[string]struct{}, len(_structNamesMap)+len(fnCtxs))
+	for name := range _structNamesMap {
+		identMap[name] = struct{}{}
+	}
+	for _, ctx := range fnCtxs {
+		identMap[ctx.fnName] = struct{}{}
 	}
-	sourceFile.GoBody.Pn(")")
+	pConstants(sourceFile, constants, identMap)
 
 	pSignalNameConstants(sourceFile)
 
-	if _optNamespace == "GLib" || _optNamespace == "GObject" {
-		err = saveGenState(genStateFile, &genState{
-			PrevNamespace: _optNamespace,
-			FuncNextId:    _funcNextIdx,
-			GetTypeNextId: _getTypeNextId,
-		})
-		if err != nil {
-			log.Fatalln(err)
-		}
-	} else if _optNamespace == "Gio" {
-		err = os.Remove(genStateFile)
-		if err != nil {
-			log.Println("WARN:", err)
-		}
-	}
-
-	if _optNamespace == "GLib" || _optNamespace == "Gio" || _optNamespace == "GObject" {
-		// 修正 gio 和 gobject 的 import
-		var temp []string
-		for _, imp := range sourceFile.GoImports {
-			if strings.HasSuffix(imp, "glib-2.0\"") ||
-				strings.HasSuffix(imp, "gobject-2.0\"") {
-				// ignore
-			} else {
-				temp = append(temp, imp)
-			}
-		}
-		sourceFile.GoImports = temp
-	} else {
-		// 修正其它更上层的包（比如 Gdk、Gtk）对 g-2.0 包的导入。
-		var temp []string
-		useG := false
-		for _, imp := range sourceFile.GoImports {
-			if strings.HasSuffix(imp, "glib-2.0\"") ||
-				strings.HasSuffix(imp, "gobject-2.0\"") ||
-				strings.HasSuffix(imp, "gio-2.0\"") {
-				// ignore
-				useG = true
-			} else {
-				temp = append(temp, imp)
-			}
-		}
-		sourceFile.GoImports = temp
-		if useG {
-			sourceFile.AddGirImport("g-2.0")
-		}
+	err = sourceFile.CheckNameCollisions(outFile)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	err = sourceFile.Save(outFile)
@@ -385,13 +405,26 @@ func main() {
 		log.Fatal("failed to save: ", err)
 	}
 
+	if md != nil {
+		md.warnUnused()
+	}
+	for _, entry := range _cfg.getUnmatchedEntries() {
+		log.Printf("WARN: config %v entry %q matches nothing in namespace %v", entry.key, entry.item, _optNamespace)
+	}
+
 	log.Printf("stat %v TODO/ALL %d/%d %.2f%%\n", _optNamespace, _numTodoFunc, _numFunc,
 		float64(_numTodoFunc)/float64(_numFunc)*100)
+
+	err = saveTodoReport(_optReportFile, _optReportHtmlFile)
+	if err != nil {
+		log.Fatal("failed to save report: ", err)
+	}
 }
 
-func pSignal(si *gi.SignalInfo) {
+func pSignal(s *SourceFile, container propContainer, isIfc bool, si *gi.SignalInfo) {
 	name := si.Name()
 	_sigNamesMap[name] = struct{}{}
+	pSignalConnect(s, container, isIfc, si)
 }
 
 func pSignalNameConstants(sf *SourceFile) {
@@ -413,20 +446,92 @@ func pSignalNameConstants(sf *SourceFile) {
 	sf.GoBody.Pn(")") // end const
 }
 
-func pConstant(constants []string, ci *gi.ConstantInfo) []string {
+// constantDef 是一个要生成的常量
+type constantDef struct {
+	name  string
+	type0 string
+	value string
+}
+
+// parseConstant 获取常量 ci 的 Go 类型和值的表达式，ok 为 false 表示不支持此常量。
+func parseConstant(ci *gi.ConstantInfo) (type0, value string, ok bool) {
+	ti := ci.Type()
+	defer ti.Unref()
+	tag := ti.Tag()
+
+	if tag == gi.TYPE_TAG_INTERFACE {
+		ii := ti.Interface()
+		defer ii.Unref()
+		// typelib 中无法获取枚举和标志类型常量的值，从 GIR 中获取。
+		switch ii.Type() {
+		case gi.INFO_TYPE_ENUM:
+			type0 = getEnumTypeName(getTypeNameWithBaseInfo(ii))
+		case gi.INFO_TYPE_FLAGS:
+			type0 = getFlagsTypeName(getTypeNameWithBaseInfo(ii))
+		default:
+			return "", "", false
+		}
+		xConstant := _xRepo.GetConstant(ci.Name())
+		if xConstant == nil {
+			return "", "", false
+		}
+		val, err := strconv.ParseInt(xConstant.Value, 10, 64)
+		if err != nil {
+			return "", "", false
+		}
+		return type0, strconv.FormatInt(val, 10), true
+	}
+
 	val := ci.Value()
-	if val == nil {
+	switch v := val.(type) {
+	case string:
+		return "string", strconv.Quote(v), true
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return "", "", false
+		}
+		return "float32", strconv.FormatFloat(float64(v), 'g', -1, 32), true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", "", false
+		}
+		return "float64", strconv.FormatFloat(v, 'g', -1, 64), true
+	case nil:
+		return "", "", false
+	}
+	type0 = getTypeWithTag(tag)
+	if type0 == "" {
+		return "", "", false
+	}
+	return type0, fmt.Sprintf("%v", val), true
+}
+
+func pConstant(constants []constantDef, ci *gi.ConstantInfo) []constantDef {
+	type0, value, ok := parseConstant(ci)
+	if !ok {
 		// 忽略这个常量
 		return constants
 	}
+	return append(constants, constantDef{
+		name:  getTypeName(ci.Name()),
+		type0: type0,
+		value: value,
+	})
+}
 
-	valStr, ok := val.(string)
-	if ok {
-		constants = append(constants, ci.Name(), strconv.Quote(valStr))
-	} else {
-		constants = append(constants, ci.Name(), fmt.Sprintf("%v", val))
+// pConstants 在一个 const 块中输出本命名空间的所有常量，如果常量的名字和 identMap 中的标识符冲突，
+// 则给它加上 _CONST 后缀。
+func pConstants(s *SourceFile, constants []constantDef, identMap map[string]struct{}) {
+	s.GoBody.Pn("// constants\nconst (")
+	for _, c := range constants {
+		name := c.name
+		if _, ok := identMap[name]; ok {
+			name += "_CONST"
+			s.GoBody.Pn("// %v is renamed to %v, name conflicts", c.name, name)
+		}
+		s.GoBody.Pn("%s %s = %s", name, c.type0, c.value)
 	}
-	return constants
+	s.GoBody.Pn(")")
 }
 
 func getFlagsTypeName(type0 string) string {
@@ -449,32 +554,156 @@ func pEnum(s *SourceFile, ei *gi.EnumInfo, isEnum bool) {
 	var type0 string
 	if isEnum {
 		s.GoBody.Pn("// Enum %v", name)
+		pDocParagraph(s.GoBody, getTypeDoc(ei.Name()))
 		type0 = getEnumTypeName(name)
 	} else {
 		// is Flags
 		s.GoBody.Pn("// Flags %v", name)
+		pDocParagraph(s.GoBody, getTypeDoc(ei.Name()))
 		type0 = getFlagsTypeName(name)
 	}
 	s.GoBody.Pn("type %s int", type0)
+
+	var xEnumInfo *xmlp.EnumInfo
+	if typeDef, _ := _xRepo.GetType(ei.Name()); typeDef != nil {
+		xEnumInfo, _ = typeDef.(*xmlp.EnumInfo)
+	}
+
+	// enumValues 用于生成 String 方法和 Parse 函数，它的元素类似 {Value: 1, Name: "GTK_ORIENTATION_VERTICAL", Nick: "vertical"}
+	var enumValues []string
+	// 成员名的前缀，错误代码枚举去掉类型名的后缀 "Enum"，
+	// 比如 IOErrorEnum 的成员是 IOErrorNotFound，和 C 中的 G_IO_ERROR_NOT_FOUND 对应。
+	memberPrefix := name
+	if isEnum && ei.ErrorDomain() != "" {
+		memberPrefix = strings.TrimSuffix(name, "Enum")
+	}
 	s.GoBody.Pn("const (")
 	num := ei.NumValue()
 	for i := 0; i < num; i++ {
 		value := ei.Value(i)
 		val := value.Value()
-		memberName := name + snake2Camel(value.Name())
+		valueName := value.Name()
+		memberName := memberPrefix + snake2Camel(valueName)
 		if memberName == type0 {
 			// 成员和类型重名了
 			memberName += "0"
 		}
+		xMember := getEnumMember(xEnumInfo, valueName)
+		if xMember != nil {
+			pDoc(s.GoBody, &xMember.Documentation)
+		}
 		s.GoBody.Pn("%s %s = %v", memberName, type0, val)
+
+		cName, nick := getEnumMemberNames(xMember, valueName)
+		enumValues = append(enumValues, fmt.Sprintf("{Value: %v, Name: %q, Nick: %q}", val, cName, nick))
 		value.Unref()
 	}
 	s.GoBody.Pn(")") // end const
 
+	pEnumHelpers(s, type0, enumValues, isEnum)
+
+	if isEnum {
+		if errDomain := ei.ErrorDomain(); errDomain != "" {
+			pErrorDomainMethods(s, type0, errDomain)
+		}
+	}
+
 	// NOTE: enum 和 flags 也有类型的
 	pGetTypeFunc(s, name, ei.Name())
 }
 
+// getEnumMember 获取枚举或标志在 GIR 中名为 valueName 的成员，找不到返回 nil。
+func getEnumMember(xEnumInfo *xmlp.EnumInfo, valueName string) *xmlp.EnumMember {
+	if xEnumInfo == nil {
+		return nil
+	}
+	for _, member := range xEnumInfo.Members {
+		if member.Name == valueName {
+			return member
+		}
+	}
+	return nil
+}
+
+// getEnumMemberNames 获取枚举或标志的成员在 C 中的名字和 nick，valueName 是 ValueInfo 的名字，
+// 如果在 GIR 中找不到此成员，即 xMember 为 nil，则用 valueName 代替。
+func getEnumMemberNames(xMember *xmlp.EnumMember, valueName string) (cName, nick string) {
+	cName = valueName
+	nick = strings.Replace(valueName, "_", "-", -1)
+	if xMember == nil {
+		return
+	}
+	if xMember.CIdentifier != "" {
+		cName = xMember.CIdentifier
+	}
+	if xMember.GlibNick != "" {
+		nick = xMember.GlibNick
+	}
+	return
+}
+
+// pEnumHelpers 为枚举或标志类型 type0 生成 String 方法和 Parse 函数，为标志类型还生成 Has、Set 和 Clear 方法。
+func pEnumHelpers(s *SourceFile, type0 string, enumValues []string, isEnum bool) {
+	varValues := "_" + type0 + "Values"
+	s.GoBody.Pn("var %v = []gi.EnumValue{", varValues)
+	for _, enumValue := range enumValues {
+		s.GoBody.Pn("%v,", enumValue)
+	}
+	s.GoBody.Pn("}") // end var
+
+	if isEnum {
+		s.GoBody.Pn("func (v %v) String() string {", type0)
+		s.GoBody.Pn("return gi.EnumString(%v, int64(v), %q)", varValues, type0)
+		s.GoBody.Pn("}")
+
+		s.GoBody.Pn("// Parse%v 根据 nick 或 C 中的名字获取枚举值。", type0)
+		s.GoBody.Pn("func Parse%v(str string) (%v, error) {", type0, type0)
+		s.GoBody.Pn("v, err := gi.ParseEnum(%v, str, %q)", varValues, type0)
+		s.GoBody.Pn("return %v(v), err", type0)
+		s.GoBody.Pn("}")
+		return
+	}
+
+	s.GoBody.Pn("func (v %v) String() string {", type0)
+	s.GoBody.Pn("return gi.FlagsString(%v, int64(v))", varValues)
+	s.GoBody.Pn("}")
+
+	s.GoBody.Pn("// Has 返回 v 是否设置了 flags 中的所有标志。")
+	s.GoBody.Pn("func (v %v) Has(flags %v) bool {", type0, type0)
+	s.GoBody.Pn("return v&flags == flags")
+	s.GoBody.Pn("}")
+
+	s.GoBody.Pn("// Set 返回设置了 flags 中的所有标志后的 v。")
+	s.GoBody.Pn("func (v %v) Set(flags %v) %v {", type0, type0, type0)
+	s.GoBody.Pn("return v | flags")
+	s.GoBody.Pn("}")
+
+	s.GoBody.Pn("// Clear 返回清除了 flags 中的所有标志后的 v。")
+	s.GoBody.Pn("func (v %v) Clear(flags %v) %v {", type0, type0, type0)
+	s.GoBody.Pn("return v &^ flags")
+	s.GoBody.Pn("}")
+
+	s.GoBody.Pn("// Parse%v 解析以 | 分隔的多个标志，每个标志可以是 nick 或者 C 中的名字。", type0)
+	s.GoBody.Pn("func Parse%v(str string) (%v, error) {", type0, type0)
+	s.GoBody.Pn("v, err := gi.ParseFlags(%v, str, %q)", varValues, type0)
+	s.GoBody.Pn("return %v(v), err", type0)
+	s.GoBody.Pn("}")
+}
+
+// pErrorDomainMethods 为错误域 errDomain 的错误代码枚举类型 type0 生成实现 gi.ErrorCode 接口的方法，
+// 这样就可以用 errors.Is(err, IOErrorNotFound) 判断 GError 的错误域和错误代码。
+func pErrorDomainMethods(s *SourceFile, type0, errDomain string) {
+	s.GoBody.Pn("func (v %v) ErrorDomain() gi.Quark {", type0)
+	s.GoBody.Pn("return gi.QuarkFromString(%q)", errDomain)
+	s.GoBody.Pn("}")
+	s.GoBody.Pn("func (v %v) ErrorCode() int {", type0)
+	s.GoBody.Pn("return int(v)")
+	s.GoBody.Pn("}")
+	s.GoBody.Pn("func (v %v) Error() string {", type0)
+	s.GoBody.Pn("return gi.ErrorCodeString(_%vValues, %q, int(v))", type0, errDomain)
+	s.GoBody.Pn("}")
+}
+
 func pStruct(s *SourceFile, si *gi.StructInfo, idxLv1 int) {
 	name := si.Name()
 
@@ -522,17 +751,19 @@ func pStruct(s *SourceFile, si *gi.StructInfo, idxLv1 int) {
 		markDeprecated(s)
 	}
 
-	s.GoBody.Pn("// Struct %s", name)
-	s.GoBody.Pn("type %s struct {", name)
+	typeName := getTypeName(name)
+	s.GoBody.Pn("// Struct %s", typeName)
+	pDocParagraph(s.GoBody, getTypeDoc(name))
+	s.GoBody.Pn("type %s struct {", typeName)
 	s.GoBody.Pn("    P unsafe.Pointer")
 	s.GoBody.Pn("}")
 
 	size := si.Size()
 	if size > 0 {
-		s.GoBody.Pn("const SizeOfStruct%v = %v", name, size)
+		s.GoBody.Pn("const SizeOfStruct%v = %v", typeName, size)
 	}
 
-	pGetTypeFunc(s, name, "")
+	pGetTypeFunc(s, typeName, name)
 
 	for idxLv2 := 0; idxLv2 < numMethods; idxLv2++ {
 		fi := si.Method(idxLv2)
@@ -540,13 +771,21 @@ func pStruct(s *SourceFile, si *gi.StructInfo, idxLv1 int) {
 	}
 
 	numFields := si.NumField()
-	if !strSliceContains(_cfg.DeniedFieldsStructs, name) && numFields > 0 {
+	var sinceVersion string
+	if doc := getTypeDoc(name); doc != nil {
+		sinceVersion = doc.Version
+	}
+	if isVersionTooNew(sinceVersion) {
+		// 字段的存取方法用到了 C 中的结构类型，旧版本的头文件中没有它。
+		s.GoBody.Pn("// omitted fields of struct %v, since %v is newer than min version %v\n",
+			name, sinceVersion, _cfg.MinVersion)
+	} else if !_cfg.isFieldsStructDenied(name) && numFields > 0 {
 		pStructPFunc(s, si)
 		for i := 0; i < numFields; i++ {
 			field := si.Field(i)
 			fieldName := field.Name()
 
-			if strSliceContains(_cfg.DeniedFields, fmt.Sprintf("%v.%v", name, fieldName)) {
+			if _cfg.isFieldDenied(fmt.Sprintf("%v.%v", name, fieldName)) {
 				s.GoBody.Pn("// denied field %v.%v\n", name, fieldName)
 				continue
 			}
@@ -558,16 +797,26 @@ func pStruct(s *SourceFile, si *gi.StructInfo, idxLv1 int) {
 					s.GoBody.Pn("// TODO: ignore struct %v field %v, bits(=%v) > 0\n", name, fieldName, xField.Bits)
 					continue
 				}
+				if xField != nil && isVersionTooNew(xField.Version) {
+					s.GoBody.Pn("// omitted field %v.%v, since %v is newer than min version %v\n",
+						name, fieldName, xField.Version, _cfg.MinVersion)
+					continue
+				}
+			}
+
+			var fieldDoc *xmlp.Documentation
+			if xField != nil {
+				fieldDoc = &xField.Documentation
 			}
 
 			flags := field.Flags()
 			if flags&gi.FIELD_IS_READABLE == gi.FIELD_IS_READABLE {
 				// is readable
-				pStructGetFunc(s, field, name)
+				pStructGetFunc(s, field, typeName, fieldDoc)
 			}
 			if flags&gi.FIELD_IS_WRITABLE == gi.FIELD_IS_WRITABLE {
 				// is writable
-				pStructSetFunc(s, field, name)
+				pStructSetFunc(s, field, typeName, fieldDoc)
 			}
 
 			field.Unref()
@@ -597,12 +846,12 @@ func pStructPFunc(s *SourceFile, si *gi.StructInfo) {
 			cTypeName = cPrefix + "_" + camel2Snake(structName) + "_t"
 		}
 	}
-	s.GoBody.Pn("\nfunc (v %v) p() %v {", structName, "*C."+cTypeName)
+	s.GoBody.Pn("\nfunc (v %v) p() %v {", getTypeName(structName), "*C."+cTypeName)
 	s.GoBody.Pn("return (*C.%v)(v.P)", cTypeName)
 	s.GoBody.Pn("}") // end func
 }
 
-func pStructGetFunc(s *SourceFile, fieldInfo *gi.FieldInfo, structName string) {
+func pStructGetFunc(s *SourceFile, fieldInfo *gi.FieldInfo, structName string, fieldDoc *xmlp.Documentation) {
 	fieldName := fieldInfo.Name()
 	var varReg VarReg
 	typeInfo := fieldInfo.Type()
@@ -613,16 +862,24 @@ func pStructGetFunc(s *SourceFile, fieldInfo *gi.FieldInfo, structName string) {
 	if fieldName == "p" {
 		getFnName = "P0"
 	}
+	isTodo := strings.Contains(parseResult.goType, "/*TODO*/")
+	if isTodo {
+		addFieldTodoItem(fieldInfo, typeInfo)
+		if pUnsupportedFieldAccessor(s, structName, getFnName, fieldDoc) {
+			return
+		}
+	}
+	pDoc(s.GoBody, fieldDoc)
 	varResult := varReg.alloc("result")
 	s.GoBody.Pn("func (v %v) %v() (%v %v) {", structName, getFnName, varResult, parseResult.goType)
-	if !strings.Contains(parseResult.goType, "/*TODO*/") {
+	if !isTodo {
 		s.GoBody.Pn("%v = %v", varResult, parseResult.expr)
 	}
 	s.GoBody.Pn("    return")
 	s.GoBody.Pn("}") // end func
 }
 
-func pStructSetFunc(s *SourceFile, fieldInfo *gi.FieldInfo, structName string) {
+func pStructSetFunc(s *SourceFile, fieldInfo *gi.FieldInfo, structName string, fieldDoc *xmlp.Documentation) {
 	fieldName := fieldInfo.Name()
 	var varReg VarReg
 	typeInfo := fieldInfo.Type()
@@ -631,8 +888,16 @@ func pStructSetFunc(s *SourceFile, fieldInfo *gi.FieldInfo, structName string) {
 	varValue := varReg.alloc("value")
 	parseResult := parseFieldType(typeInfo, fieldName, varValue)
 	setFnName := "Set" + snake2Camel(fieldName)
+	isTodo := strings.Contains(parseResult.goType, "/*TODO*/")
+	if isTodo {
+		addFieldTodoItem(fieldInfo, typeInfo)
+		if pUnsupportedFieldAccessor(s, structName, setFnName, fieldDoc) {
+			return
+		}
+	}
+	pDoc(s.GoBody, fieldDoc)
 	s.GoBody.Pn("func (v %v) %v(%v %v) {", structName, setFnName, varValue, parseResult.goType)
-	if !strings.Contains(parseResult.goType, "/*TODO*/") {
+	if !isTodo {
 		for _, line := range parseResult.setLines {
 			s.GoBody.Pn("%v", line)
 		}
@@ -640,6 +905,26 @@ func pStructSetFunc(s *SourceFile, fieldInfo *gi.FieldInfo, structName string) {
 	s.GoBody.Pn("}") // end func
 }
 
+// pUnsupportedFieldAccessor 按配置的 Unsupported 处理类型不能正确处理的字段的存取方法 methodName，
+// omit 时只打印注释，stub 时打印不含字段值、只返回 gi.ErrNotSupported 的桩方法，这两种情况返回 true，
+// keep 时什么都不打印，返回 false，由调用者照常生成什么都不做的方法。
+func pUnsupportedFieldAccessor(s *SourceFile, structName, methodName string, fieldDoc *xmlp.Documentation) bool {
+	switch _cfg.Unsupported {
+	case unsupportedOmit:
+		s.GoBody.Pn("// omitted unsupported field accessor %v.%v\n", structName, methodName)
+		return true
+	case unsupportedStub:
+		pDoc(s.GoBody, fieldDoc)
+		s.GoBody.Pn("//\n// unsupported field accessor, always returns gi.ErrNotSupported")
+		s.GoBody.Pn("func (v %v) %v() (err error) {", structName, methodName)
+		s.GoBody.Pn("err = gi.ErrNotSupported")
+		s.GoBody.Pn("return")
+		s.GoBody.Pn("}") // end func
+		return true
+	}
+	return false
+}
+
 type parseFieldTypeResult struct {
 	goType   string
 	field    string
@@ -721,14 +1006,11 @@ func parseFieldType(ti *gi.TypeInfo, fieldName string, varValue string) *parseFi
 	}
 }
 
-// 给 XXXGetType 用的 id
-var _getTypeNextId int
-
 /*
 打印类型的 GType 类型获取方法，比如 gio.ActionEntry 的：
 
 func ActionEntryGetType() gi.GType {
-	ret := _I.GetGType1(194, "Gio", "ActionEntry")
+	ret := _I.GetGType1("Gio", "ActionEntry")
 	return ret
 }
 */
@@ -736,23 +1018,21 @@ func pGetTypeFunc(s *SourceFile, name, realName string) {
 	if realName == "" {
 		realName = name
 	}
-	if strSliceContains(_cfg.NoGetType, name) {
+	if _cfg.isNoGetType(realName) {
 		s.GoBody.Pn("// noGetType %s\n", name)
-		_getTypeNextId++
 		return
 	}
 
 	s.GoBody.Pn("func %sGetType() gi.GType {", name)
 
 	if _optNamespace == "GObject" || _optNamespace == "Gio" {
-		s.GoBody.Pn("ret := _I.GetGType1(%v, %q, %q)", _getTypeNextId, _optNamespace, realName)
+		s.GoBody.Pn("ret := _I.GetGType1(%q, %q)", _optNamespace, realName)
 	} else {
-		s.GoBody.Pn("ret := _I.GetGType(%v, %q)", _getTypeNextId, realName)
+		s.GoBody.Pn("ret := _I.GetGType(%q)", realName)
 	}
 
 	s.GoBody.Pn("return ret")
 	s.GoBody.Pn("}")
-	_getTypeNextId++
 }
 
 func pUnion(s *SourceFile, ui *gi.UnionInfo, idxLv1 int) {
@@ -760,17 +1040,19 @@ func pUnion(s *SourceFile, ui *gi.UnionInfo, idxLv1 int) {
 		markDeprecated(s)
 	}
 	name := ui.Name()
-	s.GoBody.Pn("// Union %s", name)
-	s.GoBody.Pn("type %s struct {", name)
+	typeName := getTypeName(name)
+	s.GoBody.Pn("// Union %s", typeName)
+	pDocParagraph(s.GoBody, getTypeDoc(name))
+	s.GoBody.Pn("type %s struct {", typeName)
 	s.GoBody.Pn("    P unsafe.Pointer")
 	s.GoBody.Pn("}")
 
 	size := ui.Size()
 	if size > 0 {
-		s.GoBody.Pn("const SizeOfUnion%v = %v", name, size)
+		s.GoBody.Pn("const SizeOfUnion%v = %v", typeName, size)
 	}
 
-	pGetTypeFunc(s, name, "")
+	pGetTypeFunc(s, typeName, name)
 
 	numMethod := ui.NumMethod()
 	for idxLv2 := 0; idxLv2 < numMethod; idxLv2++ {
@@ -784,33 +1066,110 @@ func pInterface(s *SourceFile, ii *gi.InterfaceInfo, idxLv1 int) {
 		markDeprecated(s)
 	}
 	name := ii.Name()
-	s.GoBody.Pn("// Interface %s", name)
-	s.GoBody.Pn("type %s struct {", name)
-	s.GoBody.Pn("    %sIfc", name)
+	typeName := getTypeName(name)
+	s.GoBody.Pn("// Interface %s", typeName)
+	pDocParagraph(s.GoBody, getTypeDoc(name))
+	s.GoBody.Pn("type %s struct {", typeName)
+	s.GoBody.Pn("    %sIfc", typeName)
 	s.GoBody.Pn("    P unsafe.Pointer")
 	s.GoBody.Pn("}") // end struct
 
-	s.GoBody.Pn("type %sIfc struct{}", name)
+	s.GoBody.Pn("type %sIfc struct{}", typeName)
 
-	s.GoBody.P("type I%s interface {", name)
-	s.GoBody.Pn("P_%s() unsafe.Pointer }", name)
-	s.GoBody.Pn("func (v %s) P_%s() unsafe.Pointer { return v.P }", name, name)
+	s.GoBody.P("type I%s interface {", typeName)
+	s.GoBody.Pn("P_%s() unsafe.Pointer }", typeName)
+	s.GoBody.Pn("func (v %s) P_%s() unsafe.Pointer { return v.P }", typeName, typeName)
 
-	pGetTypeFunc(s, name, "")
+	pGetTypeFunc(s, typeName, name)
 
+	var methodSigs []string
+	var fnCtxs []*pFuncContext
 	numMethod := ii.NumMethod()
 	for idxLv2 := 0; idxLv2 < numMethod; idxLv2++ {
 		fi := ii.Method(idxLv2)
-		pFunction(s, fi, idxLv1, idxLv2)
+		fnCtx := pFunction(s, fi, idxLv1, idxLv2)
+		fnCtxs = append(fnCtxs, fnCtx)
+		if methodSig := fnCtx.methodSig(); methodSig != "" {
+			methodSigs = append(methodSigs, methodSig)
+		}
 	}
 
+	pInterfaceMethods(s, ii, methodSigs)
+	recordOwnMethodSigs(gi.ToBaseInfo(ii), fnCtxs)
+
+	var xProps []*xmlp.Property
+	var xFuncs []*xmlp.FunctionInfo
+	if typeDef, _ := _xRepo.GetType(name); typeDef != nil {
+		if xIfcInfo, ok := typeDef.(*xmlp.InterfaceInfo); ok {
+			xProps = xIfcInfo.Properties
+			// 复制到新的切片，避免 append 写入 xIfcInfo.Methods 的底层数组
+			xFuncs = make([]*xmlp.FunctionInfo, 0, len(xIfcInfo.Methods)+len(xIfcInfo.Functions))
+			xFuncs = append(xFuncs, xIfcInfo.Methods...)
+			xFuncs = append(xFuncs, xIfcInfo.Functions...)
+		}
+	}
+	fnCtxs = append(fnCtxs, pAsyncWrappers(s, fnCtxs, xFuncs)...)
+	pContextVariants(s, fnCtxs)
+	pProperties(s, ii, true, xProps, fnCtxs)
+
+	pInterfaceVFuncs(s, ii)
+
 	numSig := ii.NumSignal()
 	for i := 0; i < numSig; i++ {
 		si := ii.Signal(i)
-		pSignal(si)
+		pSignal(s, ii, true, si)
+		si.Unref()
 	}
 }
 
+/*
+打印接口的方法集接口和 AsXxx 转换方法，比如 Gio.File 的：
+
+type FileMethods interface {
+	IFile
+	AsFile() File
+	AppendTo(flags FileCreateFlags, cancellable ICancellable) (result FileOutputStream, err error)
+	...
+}
+
+func (v *FileIfc) AsFile() File {
+	return File{P: *(*unsafe.Pointer)(unsafe.Pointer(v))}
+}
+
+实现了此接口的对象或者它的祖先类型嵌入了 FileIfc，所以对象的指针一般满足 FileMethods 接口，
+pMethodSetAssertions 为每个对象生成 var _ FileMethods = (*Obj)(nil) 在编译时检查。
+对象的方法遮盖了同名而签名不同的接口方法时，比如 Gtk.CellAreaBox.PackStart 遮盖了 CellLayoutIfc.PackStart，
+对象的指针不满足它，这时不生成检查，只生成说明原因的注释。
+*/
+func pInterfaceMethods(s *SourceFile, ii *gi.InterfaceInfo, methodSigs []string) {
+	name := getTypeName(ii.Name())
+	s.GoBody.Pn("// %vMethods 是接口 %v 的方法集，包括它依赖的接口的方法，实现了 %v 的对象的指针一般满足它，",
+		name, name, name)
+	s.GoBody.Pn("// 对象的同名方法遮盖了接口的方法时除外，见对象所在的文件中的 var _ %vMethods 检查。", name)
+	s.GoBody.Pn("type %vMethods interface {", name)
+	s.GoBody.Pn("I%v", name)
+
+	numPrereq := ii.NumPrerequisite()
+	for i := 0; i < numPrereq; i++ {
+		bi := ii.Prerequisite(i)
+		if bi.Type() == gi.INFO_TYPE_INTERFACE {
+			s.GoBody.Pn("%vMethods", getTypeNameWithBaseInfo(bi))
+		}
+		bi.Unref()
+	}
+
+	s.GoBody.Pn("As%v() %v", name, name)
+	for _, methodSig := range methodSigs {
+		s.GoBody.Pn("%v", methodSig)
+	}
+	s.GoBody.Pn("}") // end interface
+
+	s.GoBody.Pn("// As%v 返回实现了此接口的对象对应的 %v 接口值", name, name)
+	s.GoBody.Pn("func (v *%vIfc) As%v() %v {", name, name, name)
+	s.GoBody.Pn("return %v{P: *(*unsafe.Pointer)(unsafe.Pointer(v))}", name)
+	s.GoBody.Pn("}") // end func
+}
+
 //  isParentImplIfc 返回是否父类型实现了 ifcInfo 接口
 func isParentImplIfc(oi *gi.ObjectInfo, ifcInfo *gi.InterfaceInfo) bool {
 	ifcGType := ifcInfo.GetGType()
@@ -837,8 +1196,10 @@ func pObject(s *SourceFile, oi *gi.ObjectInfo, idxLv1 int) {
 	if oi.IsDeprecated() {
 		markDeprecated(s)
 	}
-	s.GoBody.Pn("// Object %s", name)
-	s.GoBody.Pn("type %s struct {", name)
+	typeName := getTypeName(name)
+	s.GoBody.Pn("// Object %s", typeName)
+	pDocParagraph(s.GoBody, getTypeDoc(name))
+	s.GoBody.Pn("type %s struct {", typeName)
 
 	var embeddedIfcs []string
 
@@ -848,9 +1209,9 @@ func pObject(s *SourceFile, oi *gi.ObjectInfo, idxLv1 int) {
 
 		// 如果父类型没有实现此接口，才嵌入它
 		if !isParentImplIfc(oi, ii) {
-			typeName := getTypeNameWithBaseInfo(gi.ToBaseInfo(ii))
-			s.GoBody.Pn("%sIfc", typeName)
-			embeddedIfcs = append(embeddedIfcs, ii.Name())
+			ifcTypeName := getTypeNameWithBaseInfo(gi.ToBaseInfo(ii))
+			s.GoBody.Pn("%sIfc", ifcTypeName)
+			embeddedIfcs = append(embeddedIfcs, getGoTypeName(ii.Namespace(), ii.Name()))
 		}
 
 		ii.Unref()
@@ -868,30 +1229,49 @@ func pObject(s *SourceFile, oi *gi.ObjectInfo, idxLv1 int) {
 
 	s.GoBody.Pn("}") // end struct
 
-	s.GoBody.P("func Wrap%s(p unsafe.Pointer) (r %s) {", name, name)
+	s.GoBody.P("func Wrap%s(p unsafe.Pointer) (r %s) {", typeName, typeName)
 	s.GoBody.P("r.P = p;")
 	s.GoBody.Pn("return }")
 
-	s.GoBody.P("type I%s interface {", name)
-	s.GoBody.Pn("P_%s() unsafe.Pointer }", name)
-	s.GoBody.Pn("func (v %s) P_%s() unsafe.Pointer { return v.P }", name, name)
+	s.GoBody.P("type I%s interface {", typeName)
+	s.GoBody.Pn("P_%s() unsafe.Pointer }", typeName)
+	s.GoBody.Pn("func (v %s) P_%s() unsafe.Pointer { return v.P }", typeName, typeName)
 
 	for _, ifc := range embeddedIfcs {
-		s.GoBody.Pn("func (v %s) P_%s() unsafe.Pointer { return v.P }", name, ifc)
+		s.GoBody.Pn("func (v %s) P_%s() unsafe.Pointer { return v.P }", typeName, ifc)
 	}
 
-	pGetTypeFunc(s, name, "")
+	pGetTypeFunc(s, typeName, name)
 
+	var fnCtxs []*pFuncContext
 	numMethod := oi.NumMethod()
 	for idxLv2 := 0; idxLv2 < numMethod; idxLv2++ {
 		fi := oi.Method(idxLv2)
-		pFunction(s, fi, idxLv1, idxLv2)
+		fnCtxs = append(fnCtxs, pFunction(s, fi, idxLv1, idxLv2))
 	}
 
+	var xProps []*xmlp.Property
+	var xFuncs []*xmlp.FunctionInfo
+	if typeDef, _ := _xRepo.GetType(name); typeDef != nil {
+		if xObjInfo, ok := typeDef.(*xmlp.ObjectInfo); ok {
+			xProps = xObjInfo.Properties
+			xFuncs = append(xFuncs, xObjInfo.Methods...)
+			xFuncs = append(xFuncs, xObjInfo.Functions...)
+			xFuncs = append(xFuncs, xObjInfo.Constructors...)
+		}
+	}
+	fnCtxs = append(fnCtxs, pAsyncWrappers(s, fnCtxs, xFuncs)...)
+	recordOwnMethodSigs(gi.ToBaseInfo(oi), fnCtxs)
+	pContextVariants(s, fnCtxs)
+	pProperties(s, oi, false, xProps, fnCtxs)
+
+	pObjectVFuncs(s, oi)
+
 	numSig := oi.NumSignal()
 	for i := 0; i < numSig; i++ {
 		si := oi.Signal(i)
-		pSignal(si)
+		pSignal(s, oi, false, si)
+		si.Unref()
 	}
 }
 
@@ -0,0 +1,352 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/electricface/go-gir3/gi"
+	"golang.org/x/xerrors"
+)
+
+/*
+元数据文件用于修正 GIR 文件中错误的注解，类似于 Vala 的 .metadata 文件，文件名为 <命名空间>-<版本>.metadata，
+比如 NM-1.0.metadata，和 config.json 放在一起。
+
+每行一条规则，行首或者空白之后的 # 开始的内容是注释，比如：
+
+	# 类型名.函数名.参数名 key=value ...
+	Setting8021x.set_ca_cert.value nullable=true
+	Setting8021x.set_ca_cert.out_format direction=out transfer=full
+	# 函数名.参数名 key=value ...
+	get_data.return array-length=length element-type=guint8
+	# 类型名.名字#种类.参数名 key=value ...
+	Widget.draw#vfunc.cr nullable=false
+
+选择器中用的都是 GIR 中的名字，参数名为 return 表示返回值。名字后面可以加上 #种类，只匹配这种可调用对象，
+种类有 function（包括方法和构造器）、vfunc、signal 和 callback。没有种类的选择器匹配所有种类，
+比如方法和同名的虚函数或信号，这种情况会在 warnUnused 中报告，应该加上种类。支持的 key 有：
+direction: in, out, inout
+transfer: none, container, full
+nullable: true, false
+array-length: 长度参数的名字或索引
+element-type: 数组和列表的元素类型，比如 utf8, gint, Gio.File*，详见 gi.ParseElemType
+key-type: GHashTable 的键的类型，格式同 element-type
+value-type: GHashTable 的值的类型，格式同 element-type
+scope: call, async, notified
+*/
+
+type metadata struct {
+	rules map[string]*metadataRule // 键是选择器
+}
+
+type metadataRule struct {
+	selector    string
+	lineNum     int
+	override    gi.Override
+	arrayLength string // 数组长度参数的名字或索引，还未转换为索引。
+	used        bool
+	// 用上了此规则的可调用对象的种类，没有种类的选择器被多种可调用对象用上时有歧义。
+	usedKinds []string
+}
+
+// 选择器中的可调用对象的种类
+var metadataKinds = []string{"function", "vfunc", "signal", "callback"}
+
+// getCallableKind 返回可调用对象 callable 在选择器中的种类
+func getCallableKind(callable *gi.CallableInfo) string {
+	switch callable.Type() {
+	case gi.INFO_TYPE_VFUNC:
+		return "vfunc"
+	case gi.INFO_TYPE_SIGNAL:
+		return "signal"
+	case gi.INFO_TYPE_CALLBACK:
+		return "callback"
+	}
+	return "function"
+}
+
+func loadMetadata(filename string) (*metadata, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	md, err := parseMetadata(f)
+	if err != nil {
+		return nil, xerrors.Errorf("parse metadata file %v: %w", filename, err)
+	}
+	log.Printf("load metadata %v ok", filename)
+	return md, nil
+}
+
+func parseMetadata(r io.Reader) (*metadata, error) {
+	md := &metadata{
+		rules: make(map[string]*metadataRule),
+	}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := stripMetadataComment(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		selector := fields[0]
+		if !strings.Contains(selector, ".") {
+			return nil, xerrors.Errorf("line %d: invalid selector %q", lineNum, selector)
+		}
+		if rule, ok := md.rules[selector]; ok {
+			return nil, xerrors.Errorf("line %d: duplicate selector %q, first defined at line %d",
+				lineNum, selector, rule.lineNum)
+		}
+		if len(fields) == 1 {
+			return nil, xerrors.Errorf("line %d: no override for selector %q", lineNum, selector)
+		}
+		if idx := strings.Index(selector, "#"); idx >= 0 {
+			kind := selector[idx+1:]
+			if dotIdx := strings.Index(kind, "."); dotIdx >= 0 {
+				kind = kind[:dotIdx]
+			} else {
+				return nil, xerrors.Errorf("line %d: invalid selector %q", lineNum, selector)
+			}
+			if !strSliceContains(metadataKinds, kind) {
+				return nil, xerrors.Errorf("line %d: unknown kind %q in selector %q", lineNum, kind, selector)
+			}
+		}
+
+		rule := &metadataRule{
+			selector: selector,
+			lineNum:  lineNum,
+		}
+		for _, field := range fields[1:] {
+			err := rule.set(field)
+			if err != nil {
+				return nil, xerrors.Errorf("line %d: %w", lineNum, err)
+			}
+		}
+		md.rules[selector] = rule
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+// stripMetadataComment 去掉行 line 中的注释，注释从行首或者空白之后的 # 开始，选择器中的 # 不是注释。
+func stripMetadataComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// 设置规则的一个 key=value
+func (rule *metadataRule) set(field string) error {
+	parts := strings.SplitN(field, "=", 2)
+	if len(parts) != 2 {
+		return xerrors.Errorf("invalid field %q, expect key=value", field)
+	}
+	key, value := parts[0], parts[1]
+	invalidValueErr := xerrors.Errorf("invalid value %q for key %v", value, key)
+	override := &rule.override
+	switch key {
+	case "direction":
+		var dir gi.Direction
+		switch value {
+		case "in":
+			dir = gi.DIRECTION_IN
+		case "out":
+			dir = gi.DIRECTION_OUT
+		case "inout":
+			dir = gi.DIRECTION_INOUT
+		default:
+			return invalidValueErr
+		}
+		override.Direction = &dir
+
+	case "transfer":
+		var transfer gi.Transfer
+		switch value {
+		case "none":
+			transfer = gi.TRANSFER_NOTHING
+		case "container":
+			transfer = gi.TRANSFER_CONTAINER
+		case "full":
+			transfer = gi.TRANSFER_EVERYTHING
+		default:
+			return invalidValueErr
+		}
+		override.Transfer = &transfer
+
+	case "nullable":
+		nullable, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalidValueErr
+		}
+		override.Nullable = &nullable
+
+	case "array-length":
+		if value == "" {
+			return invalidValueErr
+		}
+		rule.arrayLength = value
+
+	case "element-type", "key-type", "value-type":
+		elemType, err := gi.ParseElemType(value)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "element-type":
+			override.ElemType = elemType
+		case "key-type":
+			override.KeyType = elemType
+		default:
+			override.ValueType = elemType
+		}
+
+	case "scope":
+		var scope gi.ScopeType
+		switch value {
+		case "call":
+			scope = gi.SCOPE_TYPE_CALL
+		case "async":
+			scope = gi.SCOPE_TYPE_ASYNC
+		case "notified":
+			scope = gi.SCOPE_TYPE_NOTIFIED
+		default:
+			return invalidValueErr
+		}
+		override.Scope = &scope
+
+	default:
+		return xerrors.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// 获取 callable 的 argName 参数的修正，作为 gi.OverrideFunc 使用。
+func (md *metadata) getOverride(callable *gi.CallableInfo, argName string) *gi.Override {
+	if callable.Namespace() != _optNamespace {
+		return nil
+	}
+	name := callable.Name()
+	if container := callable.Container(); container != nil {
+		name = container.Name() + "." + name
+	}
+	rule := md.findRule(getCallableKind(callable), name, argName)
+	if rule == nil {
+		return nil
+	}
+
+	if rule.arrayLength != "" {
+		// 把数组长度参数的名字转换为索引
+		idx, err := strconv.Atoi(rule.arrayLength)
+		if err != nil {
+			idx = getArgIndex(callable, rule.arrayLength)
+		}
+		if idx >= 0 && idx < callable.NumArg() {
+			rule.override.ArrayLength = &idx
+		} else {
+			log.Printf("WARN: metadata line %d: not found array length argument %q for %v",
+				rule.lineNum, rule.arrayLength, name)
+		}
+		rule.arrayLength = ""
+	}
+	return &rule.override
+}
+
+// findRule 返回种类为 kind、名字为 name 的可调用对象的 argName 参数的规则，并记录规则被用上，
+// 带种类的选择器优先。
+func (md *metadata) findRule(kind, name, argName string) *metadataRule {
+	rule := md.rules[name+"#"+kind+"."+argName]
+	if rule == nil {
+		rule = md.rules[name+"."+argName]
+	}
+	if rule == nil {
+		return nil
+	}
+	rule.used = true
+	if !strSliceContains(rule.usedKinds, kind) {
+		rule.usedKinds = append(rule.usedKinds, kind)
+	}
+	return rule
+}
+
+// 根据参数名获取参数的索引，找不到返回 -1。
+func getArgIndex(callable *gi.CallableInfo, argName string) int {
+	numArgs := callable.NumArg()
+	for i := 0; i < numArgs; i++ {
+		argInfo := callable.Arg(i)
+		name := argInfo.Name()
+		argInfo.Unref()
+		if name == argName {
+			return i
+		}
+	}
+	return -1
+}
+
+// 报告没有用上的规则，一般是选择器写错了，还报告被多种可调用对象用上的有歧义的规则。
+func (md *metadata) warnUnused() {
+	for _, msg := range md.getWarnings() {
+		log.Printf("WARN: %v", msg)
+	}
+}
+
+// getWarnings 返回 warnUnused 报告的内容，按行号排序。
+func (md *metadata) getWarnings() []string {
+	rules := make([]*metadataRule, 0, len(md.rules))
+	for _, rule := range md.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].lineNum < rules[j].lineNum
+	})
+	var result []string
+	for _, rule := range rules {
+		if !rule.used {
+			result = append(result, fmt.Sprintf("metadata line %d: selector %q is never used",
+				rule.lineNum, rule.selector))
+		} else if len(rule.usedKinds) > 1 {
+			result = append(result, fmt.Sprintf("metadata line %d: selector %q is ambiguous, it matches %v, "+
+				"add #kind after the name", rule.lineNum, rule.selector, strings.Join(rule.usedKinds, ", ")))
+		}
+	}
+	return result
+}
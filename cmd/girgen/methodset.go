@@ -0,0 +1,229 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/electricface/go-gir3/gi"
+)
+
+// 当前命名空间中的对象和接口自己定义的方法的签名，键是 "命名空间.名字"，值的键是方法名。
+// 用于检查对象的指针是否满足它实现的接口的 XxxMethods 接口。
+var _ownMethodSigs = make(map[string]map[string]string)
+
+// recordOwnMethodSigs 记录类型 bi 生成的方法的签名
+func recordOwnMethodSigs(bi *gi.BaseInfo, fnCtxs []*pFuncContext) {
+	sigs := make(map[string]string)
+	for _, ctx := range fnCtxs {
+		if sig := ctx.methodSig(); sig != "" {
+			sigs[ctx.fnName] = sig
+		}
+	}
+	_ownMethodSigs[bi.Namespace()+"."+bi.Name()] = sigs
+}
+
+// methodOwner 是对象的指针的方法集中某一深度上的一组方法，来自对象或其祖先类型自己定义的方法，或者嵌入的 XxxIfc。
+type methodOwner struct {
+	name string            // 用于报告的名字，比如 CellAreaBox, CellLayoutIfc
+	sigs map[string]string // 方法名到签名的映射，不在当前命名空间中的类型为 nil，当作没有方法。
+}
+
+func newMethodOwner(bi *gi.BaseInfo, suffix string) methodOwner {
+	return methodOwner{
+		name: getGoTypeName(bi.Namespace(), bi.Name()) + suffix,
+		sigs: _ownMethodSigs[bi.Namespace()+"."+bi.Name()],
+	}
+}
+
+// findMethodConflicts 返回对象的指针的方法集中遮盖了接口的方法 ifcSigs 或者与之冲突的方法，元素是 "类型.方法"。
+// levels[d] 是方法集中深度为 d 的方法，对象自己的方法深度为 0，嵌入的父类型的方法深度为 1，以此类推，
+// 接口的方法在深度 embedDepth 上。比接口的方法浅的同名方法签名不同时遮盖接口的方法，
+// 深度相同的同名方法使两者都不在方法集中。
+func findMethodConflicts(ifcSigs map[string]string, levels [][]methodOwner, embedDepth int) []string {
+	names := make([]string, 0, len(ifcSigs))
+	for name := range ifcSigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []string
+	for _, name := range names {
+	levelLoop:
+		for depth, owners := range levels {
+			if depth > embedDepth {
+				break
+			}
+			for _, owner := range owners {
+				sig, ok := owner.sigs[name]
+				if !ok {
+					continue
+				}
+				if depth == embedDepth || !isSameSigType(sig, ifcSigs[name]) {
+					result = append(result, owner.name+"."+name)
+				}
+				break levelLoop
+			}
+		}
+	}
+	return result
+}
+
+// isSameSigType 返回方法签名 sig1 和 sig2 的参数和返回值的类型是否相同，不比较参数和返回值的名字。
+func isSameSigType(sig1, sig2 string) bool {
+	return getSigType(sig1) == getSigType(sig2)
+}
+
+// getSigType 返回方法签名 sig 去掉方法名、参数名和返回值名后的函数类型，比如
+// "PackStart(cell ICellRenderer, expand bool) " => "func(ICellRenderer, bool)"，不能解析时返回 sig。
+func getSigType(sig string) string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\nfunc "+sig+" {}\n", 0)
+	if err != nil {
+		return sig
+	}
+	ft := file.Decls[0].(*ast.FuncDecl).Type
+	var buf strings.Builder
+	buf.WriteString("func(")
+	writeFieldTypes(&buf, ft.Params)
+	buf.WriteString(")(")
+	writeFieldTypes(&buf, ft.Results)
+	buf.WriteString(")")
+	return buf.String()
+}
+
+func writeFieldTypes(buf *strings.Builder, fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	first := true
+	for _, field := range fields.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			if !first {
+				buf.WriteString(", ")
+			}
+			first = false
+			_ = format.Node(buf, token.NewFileSet(), field.Type)
+		}
+	}
+}
+
+// getMethodLevels 返回对象 oi 的指针的方法集中直到接口 ii 的 XxxIfc 所在深度的各层方法，以及这个深度。
+// 对象 oi 和它的祖先类型中，第一个父类型没有实现 ii 的类型嵌入了 XxxIfc，这一层还有它的父类型的方法
+// 和它嵌入的其它 XxxIfc 的方法。找不到嵌入 XxxIfc 的类型时 ok 为 false。
+func getMethodLevels(oi *gi.ObjectInfo, ii *gi.InterfaceInfo) (levels [][]methodOwner, embedDepth int, ok bool) {
+	c := oi
+	for c != nil {
+		levels = append(levels, []methodOwner{newMethodOwner(gi.ToBaseInfo(c), "")})
+		if !isParentImplIfc(c, ii) {
+			var level []methodOwner
+			parent := c.Parent()
+			if parent != nil {
+				level = append(level, newMethodOwner(gi.ToBaseInfo(parent), ""))
+				parent.Unref()
+			}
+			numIfcs := c.NumInterface()
+			for i := 0; i < numIfcs; i++ {
+				other := c.Interface(i)
+				if other.GetGType() != ii.GetGType() && !isParentImplIfc(c, other) {
+					level = append(level, newMethodOwner(gi.ToBaseInfo(other), "Ifc"))
+				}
+				other.Unref()
+			}
+			levels = append(levels, level)
+			embedDepth = len(levels) - 1
+			ok = true
+			break
+		}
+		parent := c.Parent()
+		if c != oi {
+			c.Unref()
+		}
+		c = parent
+	}
+	if c != nil && c != oi {
+		c.Unref()
+	}
+	return
+}
+
+// getMethodSetConflicts 返回对象 oi 的指针的方法集中遮盖了接口 ii 的 XxxMethods 中的方法或者与之冲突的方法，
+// 包括 ii 依赖的接口的方法，没有冲突时 *Obj 满足 XxxMethods。
+func getMethodSetConflicts(oi *gi.ObjectInfo, ii *gi.InterfaceInfo) []string {
+	var result []string
+	levels, embedDepth, ok := getMethodLevels(oi, ii)
+	if !ok {
+		return []string{"(" + getGoTypeName(ii.Namespace(), ii.Name()) + "Ifc not embedded)"}
+	}
+	ifcSigs := _ownMethodSigs[ii.Namespace()+"."+ii.Name()]
+	result = append(result, findMethodConflicts(ifcSigs, levels, embedDepth)...)
+
+	numPrereq := ii.NumPrerequisite()
+	for i := 0; i < numPrereq; i++ {
+		bi := ii.Prerequisite(i)
+		if bi.Type() == gi.INFO_TYPE_INTERFACE {
+			for _, conflict := range getMethodSetConflicts(oi, gi.ToInterfaceInfo(bi)) {
+				if !strSliceContains(result, conflict) {
+					result = append(result, conflict)
+				}
+			}
+		}
+		bi.Unref()
+	}
+	return result
+}
+
+// pMethodSetAssertions 为当前命名空间中的每个对象和它实现的每个接口打印 var _ XxxMethods = (*Obj)(nil)，
+// 使 XxxMethods 的文档中的保证在编译时被检查。对象的方法遮盖了接口的方法时不打印，只打印注释说明原因。
+// 它应该在打印完所有类型后被调用，这时已经记录了所有对象和接口的方法的签名。
+func pMethodSetAssertions(s *SourceFile, repo *gi.Repository) {
+	numInfos := repo.NumInfo(_optNamespace)
+	for i := 0; i < numInfos; i++ {
+		bi := repo.Info(_optNamespace, i)
+		if bi.Type() == gi.INFO_TYPE_OBJECT {
+			oi := gi.ToObjectInfo(bi)
+			objName := getTypeName(oi.Name())
+			numIfcs := oi.NumInterface()
+			for j := 0; j < numIfcs; j++ {
+				ii := oi.Interface(j)
+				ifcName := getTypeNameWithBaseInfo(gi.ToBaseInfo(ii))
+				conflicts := getMethodSetConflicts(oi, ii)
+				if len(conflicts) == 0 {
+					s.GoBody.Pn("var _ %vMethods = (*%v)(nil)", ifcName, objName)
+				} else {
+					s.GoBody.Pn("// *%v does not satisfy %vMethods, shadowed or conflicting methods: %v",
+						objName, ifcName, strings.Join(conflicts, ", "))
+				}
+				ii.Unref()
+			}
+		}
+		bi.Unref()
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// 模块模式，指定了 -module-root 时，生成的包都放在模块根目录下，不再使用 GOPATH。
+var _optModuleRoot string
+var _optModulePath string
+
+// 新建的 go.mod 中的 go 指令的版本
+const goModGoVersion = "1.13"
+
+func isModuleMode() bool {
+	return _optModuleRoot != ""
+}
+
+// setupModule 在模块模式下确定模块路径并创建或更新 go.mod，模块路径也是生成的包的导入路径前缀 _girPkgPath。
+// 模块路径依次取自 -module-path 参数、已有的 go.mod 和 _girPkgPath。
+func setupModule() error {
+	root, err := filepath.Abs(_optModuleRoot)
+	if err != nil {
+		return err
+	}
+	_optModuleRoot = root
+
+	goModFile := filepath.Join(root, "go.mod")
+	content, err := ioutil.ReadFile(goModFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	modPath := _optModulePath
+	if modPath == "" {
+		modPath = getGoModModulePath(content)
+	}
+	if modPath == "" {
+		modPath = _girPkgPath
+	}
+	_optModulePath = modPath
+	_girPkgPath = modPath
+
+	newContent := updateGoMod(content, modPath)
+	if bytes.Equal(content, newContent) {
+		return nil
+	}
+	err = os.MkdirAll(root, 0755)
+	if err != nil {
+		return err
+	}
+	log.Printf("write %v, module %v", goModFile, modPath)
+	err = ioutil.WriteFile(goModFile, newContent, 0644)
+	if err != nil {
+		return xerrors.Errorf("write go.mod: %w", err)
+	}
+	return nil
+}
+
+// getGoModModulePath 返回 go.mod 的内容 content 中的模块路径，没有则返回空字符串。
+func getGoModModulePath(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			modPath := fields[1]
+			if unquoted, err := strconv.Unquote(modPath); err == nil {
+				modPath = unquoted
+			}
+			return modPath
+		}
+	}
+	return ""
+}
+
+// updateGoMod 把 go.mod 的内容 content 中的模块路径改为 modPath，其它内容保持不变，
+// content 为空则生成新的 go.mod。
+func updateGoMod(content []byte, modPath string) []byte {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return []byte("module " + modPath + "\n\ngo " + goModGoVersion + "\n")
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			if getGoModModulePath([]byte(line)) == modPath {
+				return content
+			}
+			lines[i] = "module " + modPath
+			return []byte(strings.Join(lines, "\n"))
+		}
+	}
+	return []byte("module " + modPath + "\n\n" + string(content))
+}
+
+// getPkgRootDir 返回生成的包的根目录，包 foo 在此目录下的 foo 目录中。
+// 模块模式下是模块根目录，否则是 $GOPATH/src/<GIR_PKG_PATH>。
+func getPkgRootDir() string {
+	if isModuleMode() {
+		return _optModuleRoot
+	}
+	return filepath.Join(getGoPath(), "src", _girPkgPath)
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/electricface/go-gir3/gi"
+)
+
+// namingRules 是一个命名空间的命名规则，来自它的配置文件中的 Renames 和 Initialisms。
+type namingRules struct {
+	renames map[string]string
+	// 首字母缩略词，比如 "URI"，键是它的驼峰形式，比如 "Uri"。
+	initialisms map[string]string
+	// 首字母缩略词的驼峰形式，从长到短排序，优先匹配长的。
+	initialismWords []string
+}
+
+func newNamingRules(cfg *config) *namingRules {
+	rules := &namingRules{
+		renames:     cfg.Renames,
+		initialisms: make(map[string]string, len(cfg.Initialisms)),
+	}
+	for _, initialism := range cfg.Initialisms {
+		if initialism == "" {
+			continue
+		}
+		word := strings.ToUpper(initialism[:1]) + strings.ToLower(initialism[1:])
+		if _, ok := rules.initialisms[word]; !ok {
+			rules.initialismWords = append(rules.initialismWords, word)
+		}
+		rules.initialisms[word] = initialism
+	}
+	sort.Slice(rules.initialismWords, func(i, j int) bool {
+		return len(rules.initialismWords[i]) > len(rules.initialismWords[j])
+	})
+	return rules
+}
+
+// 当前命名空间的命名规则
+var _naming = &namingRules{}
+
+// 依赖的命名空间的命名规则，键是命名空间名（不含版本），由 loadDepNaming 加载。
+var _depNaming = make(map[string]*namingRules)
+
+func initNaming(cfg *config) {
+	_naming = newNamingRules(cfg)
+}
+
+// loadDepNaming 从依赖的命名空间 deps 各自的配置文件中加载它们的命名规则，这样引用它们的类型时得到的名字
+// 和它们自己的包中生成的一致。deps 的元素是 "名字-版本"，getConfigFile 返回命名空间的配置文件，
+// 配置文件不存在的命名空间没有重命名和首字母缩略词。还会加载它们的 Unsupported 配置，见 getUnsupportedMode。
+func loadDepNaming(deps []string, getConfigFile func(ns, version string) string) error {
+	for _, dep := range deps {
+		idx := strings.LastIndex(dep, "-")
+		if idx < 0 {
+			continue
+		}
+		ns, version := dep[:idx], dep[idx+1:]
+		if ns == _optNamespace {
+			continue
+		}
+		var cfg config
+		err := loadConfig(getConfigFile(ns, version), &cfg)
+		if err != nil {
+			return err
+		}
+		_depNaming[ns] = newNamingRules(&cfg)
+		_depUnsupported[ns] = cfg.Unsupported
+	}
+	return nil
+}
+
+// applyInitialisms 把驼峰形式的名字中的首字母缩略词替换掉，比如 "NewUriFromString" => "NewURIFromString"。
+// 单词的结尾必须是名字的结尾，或者后面跟着大写字母、数字或 '_'，所以 "Identity" 中的 "Id" 不会被替换。
+// 单词的开头必须是名字的开头，或者前面是小写字母、数字、'_' 或者一串大写字母的结尾，
+// 所以 "TId" 中的 "Id" 不会被替换，而 "XMLId" 中的会。
+// 使用当前命名空间的首字母缩略词。
+func applyInitialisms(name string) string {
+	return _naming.applyInitialisms(name)
+}
+
+func (rules *namingRules) applyInitialisms(name string) string {
+	if len(rules.initialismWords) == 0 {
+		return name
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(name); {
+		matched := false
+		for _, word := range rules.initialismWords {
+			if !strings.HasPrefix(name[i:], word) {
+				continue
+			}
+			if !isWordStart(name, i) {
+				continue
+			}
+			end := i + len(word)
+			if end < len(name) {
+				c := rune(name[end])
+				if !unicode.IsUpper(c) && !unicode.IsDigit(c) && c != '_' {
+					continue
+				}
+			}
+			buf.WriteString(rules.initialisms[word])
+			i = end
+			matched = true
+			break
+		}
+		if !matched {
+			buf.WriteByte(name[i])
+			i++
+		}
+	}
+	return buf.String()
+}
+
+// isWordStart 返回驼峰形式的名字 name 中位置 i 是否可以是一个单词的开头。
+func isWordStart(name string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	c := rune(name[i-1])
+	if unicode.IsLower(c) || unicode.IsDigit(c) || c == '_' {
+		return true
+	}
+	// 前一个字符是大写字母，只有它也在一串大写字母中时，它才不是这个单词的开头
+	return unicode.IsUpper(c) && i >= 2 && unicode.IsUpper(rune(name[i-2]))
+}
+
+// getGoTypeName 获取命名空间 ns 中的名为 name 的类型在 Go 中的名字，不含包前缀。
+// 使用 ns 自己的命名规则，先查找它的配置中的重命名表，键是 "命名空间.类型名" 或者类型名；
+// 对于其它命名空间的类型，再查找当前配置中键为 "命名空间.类型名" 的重命名；找不到再应用首字母缩略词规则。
+func getGoTypeName(ns, name string) string {
+	// isOwn 表示 rules 是 ns 自己的命名规则
+	rules, isOwn := _naming, ns == _optNamespace
+	if depRules, ok := _depNaming[ns]; ok && !isOwn {
+		rules, isOwn = depRules, true
+	}
+	if goName, ok := rules.renames[ns+"."+name]; ok {
+		return goName
+	}
+	if isOwn {
+		if goName, ok := rules.renames[name]; ok {
+			return goName
+		}
+	}
+	if goName, ok := _naming.renames[ns+"."+name]; ok {
+		return goName
+	}
+	if !unicode.IsLetter(rune(name[0])) {
+		// 如果第一个字符不是单词，则加上命名空间前缀
+		name = ns + name
+	}
+	return rules.applyInitialisms(name)
+}
+
+// 函数重命名前的名字到重命名后的名字的映射，名字的形式和 DeniedFuncs 中的一样，见 pFuncContext.identifyName。
+var _funcRenames = make(map[string]string)
+
+// recordFuncRename 记录 container 中的函数从 oldName 重命名为了 newName，container 可以为 nil。
+func recordFuncRename(container *gi.BaseInfo, oldName, newName string) {
+	if oldName == newName {
+		return
+	}
+	if container != nil {
+		oldName = container.Name() + "." + oldName
+		newName = container.Name() + "." + newName
+	}
+	_funcRenames[oldName] = newName
+}
+
+// getDeniedFuncsRenameWarnings 返回 cfg 的 DeniedFuncs 中因为函数被重命名而匹配不到任何函数的项的警告，
+// DeniedFuncs 中的项使用重命名后的名字。需要在生成完所有的函数之后调用。
+func getDeniedFuncsRenameWarnings(cfg *config) []string {
+	var warnings []string
+	for _, entry := range cfg.getUnmatchedEntries() {
+		if entry.key != "DeniedFuncs" {
+			continue
+		}
+		if newName, ok := _funcRenames[entry.item]; ok {
+			warnings = append(warnings, fmt.Sprintf("DeniedFuncs entry %q matches nothing, "+
+				"since the function is renamed to %q", entry.item, newName))
+		}
+	}
+	return warnings
+}
+
+// checkNameCollisions 检查生成的代码 src 中的名字冲突，包括包级别的名字冲突，同一类型的方法之间，
+// 以及方法和字段之间的冲突，返回冲突的描述列表。otherFiles 是同一个包中手写的文件，键是文件名，
+// 生成的代码和它们之间的冲突也会被检查。
+func checkNameCollisions(src []byte, otherFiles map[string][]byte) ([]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	files := []*ast.File{file}
+
+	var otherNames []string
+	for name := range otherFiles {
+		otherNames = append(otherNames, name)
+	}
+	sort.Strings(otherNames)
+	for _, name := range otherNames {
+		otherFile, err := parser.ParseFile(fset, name, otherFiles[name], 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, otherFile)
+	}
+
+	// 生成的代码中的位置只有行号，其它文件中的位置有文件名和行号
+	posStr := func(pos token.Pos) string {
+		position := fset.Position(pos)
+		if position.Filename == "" {
+			return fmt.Sprintf("line %v", position.Line)
+		}
+		return fmt.Sprintf("%v:%v", position.Filename, position.Line)
+	}
+
+	var collisions []string
+	// 键是名字，值是第一次定义它的位置
+	declared := make(map[string]token.Pos)
+	declare := func(name string, pos token.Pos) {
+		if name == "_" || name == "init" {
+			return
+		}
+		if prevPos, ok := declared[name]; ok {
+			collisions = append(collisions, fmt.Sprintf("%v declared at %v and %v",
+				name, posStr(prevPos), posStr(pos)))
+			return
+		}
+		declared[name] = pos
+	}
+
+	for _, file := range files {
+		declareFile(file, declare)
+	}
+	return collisions, nil
+}
+
+// declareFile 对文件 file 中的包级别的名字、方法和字段调用 declare，方法和字段的名字带有类型名前缀。
+func declareFile(file *ast.File, declare func(name string, pos token.Pos)) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				name = getRecvTypeName(decl.Recv.List[0].Type) + "." + name
+			}
+			declare(name, decl.Name.Pos())
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					declare(spec.Name.Name, spec.Name.Pos())
+					if structType, ok := spec.Type.(*ast.StructType); ok {
+						for _, field := range structType.Fields.List {
+							for _, fieldName := range field.Names {
+								declare(spec.Name.Name+"."+fieldName.Name, fieldName.Pos())
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					for _, ident := range spec.Names {
+						declare(ident.Name, ident.Pos())
+					}
+				}
+			}
+		}
+	}
+}
+
+func getRecvTypeName(expr ast.Expr) string {
+	if starExpr, ok := expr.(*ast.StarExpr); ok {
+		expr = starExpr.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
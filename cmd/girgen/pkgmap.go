@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// goPackage 是命名空间对应的 Go 包
+type goPackage struct {
+	Path string // 导入路径，比如 github.com/electricface/go-gir/gtk-3.0
+	Name string // 包名，比如 gtk，为空则是命名空间名的小写
+}
+
+// 合并到 g 包中的命名空间
+func isGNamespace(ns string) bool {
+	switch ns {
+	case "GLib", "GObject", "Gio":
+		return true
+	}
+	return false
+}
+
+// getGirImportPath 返回 _girPkgPath 下的目录 dir 中的包的导入路径，比如 dir 为 gtk-3.0 或 gi。
+func getGirImportPath(dir string) string {
+	return _girPkgPath + "/" + dir
+}
+
+// getGoPackageName 返回命名空间 ns （不含版本）对应的 Go 包名，比如 ns 为 Gtk，结果为 gtk。
+func getGoPackageName(ns string) string {
+	if _cfg != nil {
+		if pkg, ok := _cfg.Packages[ns]; ok && pkg.Name != "" {
+			return pkg.Name
+		}
+	}
+	if isGNamespace(ns) {
+		return "g"
+	}
+	return strings.ToLower(ns)
+}
+
+// getGoPackage 返回命名空间 ns （不含版本）对应的 Go 包，优先使用配置中的 Packages，
+// 否则是 _girPkgPath 下的 "名字-版本" 的小写，GLib、GObject 和 Gio 在 g-2.0 中。
+// 不知道 ns 的版本时返回的 Path 为空。
+func getGoPackage(ns string) goPackage {
+	name := getGoPackageName(ns)
+	if _cfg != nil {
+		if pkg, ok := _cfg.Packages[ns]; ok {
+			return goPackage{Path: pkg.Path, Name: name}
+		}
+	}
+	if isGNamespace(ns) {
+		return goPackage{Path: getGirImportPath("g-2.0"), Name: name}
+	}
+
+	version := ""
+	if ns == _optNamespace {
+		version = _optVersion
+	} else {
+		for _, dep := range _deps {
+			if strings.HasPrefix(dep, ns+"-") {
+				version = strings.TrimPrefix(dep, ns+"-")
+				break
+			}
+		}
+	}
+	if version == "" {
+		return goPackage{Name: name}
+	}
+	return goPackage{Path: getGirImportPath(strings.ToLower(ns + "-" + version)), Name: name}
+}
+
+// importExpr 返回给 SourceFile.AddGoImport 的参数，包名和导入路径的最后一个元素不同时要加上别名。
+func (pkg goPackage) importExpr() string {
+	base := path.Base(pkg.Path)
+	if base == pkg.Name || strings.HasPrefix(base, pkg.Name+"-") {
+		return pkg.Path
+	}
+	return pkg.Name + "," + pkg.Path
+}
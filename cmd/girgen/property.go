@@ -0,0 +1,384 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/electricface/go-gir3/cmd/girgen/xmlp"
+	"github.com/electricface/go-gir3/gi"
+)
+
+// propContainer 是拥有属性的类型，即 *gi.ObjectInfo 和 *gi.InterfaceInfo。
+type propContainer interface {
+	Name() string
+	NumProperty() int
+	Property(n int) *gi.PropertyInfo
+	FindMethod(name string) *gi.FunctionInfo
+}
+
+// pProperties 给对象或者接口的属性生成类型化的存取方法 GetPropXxx 和 SetPropXxx。
+// xProps 是从 gir 文件中解析出来的属性，用于得到属性的 getter 和 setter 方法，
+// fnCtxs 是为 container 的方法生成 Go 方法时的 pFuncContext，用于判断 getter 和 setter 方法是否生成了。
+func pProperties(s *SourceFile, container propContainer, isIfc bool, xProps []*xmlp.Property,
+	fnCtxs []*pFuncContext) {
+	xPropMap := make(map[string]*xmlp.Property, len(xProps))
+	for _, xProp := range xProps {
+		xPropMap[xProp.Name] = xProp
+	}
+
+	num := container.NumProperty()
+	for i := 0; i < num; i++ {
+		pi := container.Property(i)
+		pProperty(s, container, isIfc, pi, xPropMap[pi.Name()], fnCtxs)
+		pi.Unref()
+	}
+}
+
+func pProperty(s *SourceFile, container propContainer, isIfc bool, pi *gi.PropertyInfo, xProp *xmlp.Property,
+	fnCtxs []*pFuncContext) {
+	flags := pi.Flags()
+	ti := pi.Type()
+	defer ti.Unref()
+
+	if xProp != nil && isVersionTooNew(xProp.Version) {
+		s.GoBody.Pn("// omitted property %v.%v, since %v is newer than min version %v\n",
+			container.Name(), pi.Name(), xProp.Version, _cfg.MinVersion)
+		return
+	}
+
+	isDeprecated := pi.IsDeprecated() || flags&gi.PARAM_DEPRECATED != 0
+	var propDoc *xmlp.Documentation
+	if xProp != nil {
+		propDoc = &xProp.Documentation
+	}
+
+	if flags&gi.PARAM_READABLE != 0 {
+		var getter string
+		if xProp != nil && xProp.Getter != "" {
+			getter = getPropAccessorMethodName(container, fnCtxs, xProp.Getter, ti, true)
+		}
+		pPropGetter(s, container.Name(), isIfc, pi.Name(), ti, getter, isDeprecated, propDoc)
+	}
+
+	// construct-only 的属性只能在构造对象时设置
+	if flags&gi.PARAM_WRITABLE != 0 && flags&gi.PARAM_CONSTRUCT_ONLY == 0 {
+		var setter string
+		if xProp != nil && xProp.Setter != "" {
+			setter = getPropAccessorMethodName(container, fnCtxs, xProp.Setter, ti, false)
+		}
+		pPropSetter(s, container.Name(), isIfc, pi.Name(), ti, setter, isDeprecated, propDoc)
+	}
+}
+
+// getPropReceiver 返回属性存取方法的接收者类型和获取对象指针的表达式
+func getPropReceiver(containerName string, isIfc bool, varV string) (receiverType, getPtrExpr string) {
+	typeName := getTypeName(containerName)
+	if isIfc {
+		return "*" + typeName + "Ifc", fmt.Sprintf("*(*unsafe.Pointer)(unsafe.Pointer(%v))", varV)
+	}
+	return typeName, varV + ".P"
+}
+
+/*
+打印属性的读取方法，比如 Gtk.Window 的 title 属性的：
+
+	func (v Window) GetPropTitle() (result string) {
+		gValue := gi.GetProperty(v.P, "title")
+		result = gValue.String()
+		gValue.Free()
+		return
+	}
+
+如果 getter 不为空，则直接调用 getter 方法。
+*/
+func pPropGetter(s *SourceFile, containerName string, isIfc bool, propName string, ti *gi.TypeInfo,
+	getter string, isDeprecated bool, propDoc *xmlp.Documentation) {
+
+	var varReg VarReg
+	varV := varReg.alloc("v")
+	varResult := varReg.alloc("result")
+	varGValue := varReg.alloc("gValue")
+	parseResult := parseGValueType(ti, varGValue, varResult, &varReg, true)
+	if parseResult == nil {
+		return
+	}
+	receiverType, getPtrExpr := getPropReceiver(containerName, isIfc, varV)
+
+	if isDeprecated {
+		markDeprecated(s)
+	}
+	fnName := "GetProp" + toCamelCase(propName, "-")
+	s.GoBody.Pn("// %v 获取属性 %q 的值", fnName, propName)
+	pDocParagraph(s.GoBody, propDoc)
+	s.GoBody.Pn("func (%v %v) %v() (%v %v) {", varV, receiverType, fnName, varResult, parseResult.type0)
+	if getter != "" {
+		s.GoBody.Pn("return %v.%v()", varV, getter)
+	} else {
+		s.GoBody.Pn("%v := gi.GetProperty(%v, %q)", varGValue, getPtrExpr, propName)
+		s.GoBody.Pn("%v", parseResult.getLine)
+		s.GoBody.Pn("%v.Free()", varGValue)
+		s.GoBody.Pn("return")
+	}
+	s.GoBody.Pn("}") // end func
+}
+
+// pPropSetter 打印属性的设置方法，如果 setter 不为空，则直接调用 setter 方法。
+func pPropSetter(s *SourceFile, containerName string, isIfc bool, propName string, ti *gi.TypeInfo,
+	setter string, isDeprecated bool, propDoc *xmlp.Documentation) {
+
+	var varReg VarReg
+	varV := varReg.alloc("v")
+	varValue := varReg.alloc("value")
+	varGValue := varReg.alloc("gValue")
+	parseResult := parseGValueType(ti, varGValue, varValue, &varReg, false)
+	if parseResult == nil {
+		return
+	}
+	receiverType, getPtrExpr := getPropReceiver(containerName, isIfc, varV)
+
+	if isDeprecated {
+		markDeprecated(s)
+	}
+	fnName := "SetProp" + toCamelCase(propName, "-")
+	s.GoBody.Pn("// %v 设置属性 %q 的值", fnName, propName)
+	pDocParagraph(s.GoBody, propDoc)
+	s.GoBody.Pn("func (%v %v) %v(%v %v) {", varV, receiverType, fnName, varValue, parseResult.inType0)
+	if setter != "" {
+		s.GoBody.Pn("%v.%v(%v)", varV, setter, varValue)
+	} else {
+		for _, line := range parseResult.beforeSetLines {
+			s.GoBody.Pn("%v", line)
+		}
+		s.GoBody.Pn("%v := gi.NewPropertyValue(%v, %q)", varGValue, getPtrExpr, propName)
+		s.GoBody.Pn("%v", parseResult.setLine)
+		s.GoBody.Pn("gi.SetProperty(%v, %q, %v)", getPtrExpr, propName, varGValue)
+		s.GoBody.Pn("%v.Free()", varGValue)
+	}
+	s.GoBody.Pn("}") // end func
+}
+
+type parseGValueTypeResult struct {
+	type0   string // 从 GValue 中取出的值的类型
+	inType0 string // 存入 GValue 的值的类型，对象和接口类型是 I 开头的接口类型
+	// 从 GValue 中取值并赋给 varGoValue 的语句
+	getLine string
+	// 在 setLine 之前执行的语句
+	beforeSetLines []string
+	// 把 varGoValue 存入 GValue 的语句
+	setLine string
+}
+
+// parseGValueType 根据类型 ti 得到和 GValue 相互转换的 Go 类型和代码，不支持此类型时返回 nil。
+// varGValue 是 gi.Value 类型的变量，varGoValue 是 Go 类型的变量。
+// dupBoxed 为 true 时，从 GValue 中取出的 boxed 类型的值是副本，用于 GValue 会被释放的情况。
+func parseGValueType(ti *gi.TypeInfo, varGValue, varGoValue string, varReg *VarReg,
+	dupBoxed bool) *parseGValueTypeResult {
+	var r parseGValueTypeResult
+	tag := ti.Tag()
+	isPtr := ti.IsPointer()
+	// Pointer 返回 boxed 类型的值的副本，PeekPointer 不复制。
+	getPointerMethod := "PeekPointer"
+	if dupBoxed {
+		getPointerMethod = "Pointer"
+	}
+
+	switch tag {
+	case gi.TYPE_TAG_UTF8, gi.TYPE_TAG_FILENAME:
+		r.type0 = "string"
+		r.getLine = fmt.Sprintf("%v = %v.String()", varGoValue, varGValue)
+		r.setLine = fmt.Sprintf("%v.SetString(%v)", varGValue, varGoValue)
+
+	case gi.TYPE_TAG_BOOLEAN:
+		r.type0 = "bool"
+		r.getLine = fmt.Sprintf("%v = %v.Bool()", varGoValue, varGValue)
+		r.setLine = fmt.Sprintf("%v.SetBool(%v)", varGValue, varGoValue)
+
+	case gi.TYPE_TAG_INT8, gi.TYPE_TAG_INT16, gi.TYPE_TAG_INT32, gi.TYPE_TAG_INT64:
+		r.type0 = getTypeWithTag(tag)
+		r.getLine = fmt.Sprintf("%v = %v(%v.Int64())", varGoValue, r.type0, varGValue)
+		r.setLine = fmt.Sprintf("%v.SetInt64(int64(%v))", varGValue, varGoValue)
+
+	case gi.TYPE_TAG_UINT8, gi.TYPE_TAG_UINT16, gi.TYPE_TAG_UINT32, gi.TYPE_TAG_UINT64,
+		gi.TYPE_TAG_UNICHAR:
+		r.type0 = getTypeWithTag(tag)
+		r.getLine = fmt.Sprintf("%v = %v(%v.Uint64())", varGoValue, r.type0, varGValue)
+		r.setLine = fmt.Sprintf("%v.SetUint64(uint64(%v))", varGValue, varGoValue)
+
+	case gi.TYPE_TAG_FLOAT, gi.TYPE_TAG_DOUBLE:
+		r.type0 = getTypeWithTag(tag)
+		r.getLine = fmt.Sprintf("%v = %v(%v.Double())", varGoValue, r.type0, varGValue)
+		r.setLine = fmt.Sprintf("%v.SetDouble(float64(%v))", varGValue, varGoValue)
+
+	case gi.TYPE_TAG_GTYPE:
+		r.type0 = "gi.GType"
+		r.getLine = fmt.Sprintf("%v = %v.GType()", varGoValue, varGValue)
+		r.setLine = fmt.Sprintf("%v.SetGType(%v)", varGValue, varGoValue)
+
+	case gi.TYPE_TAG_VOID:
+		if !isPtr {
+			return nil
+		}
+		r.type0 = "unsafe.Pointer"
+		r.getLine = fmt.Sprintf("%v = %v.%v()", varGoValue, varGValue, getPointerMethod)
+		r.setLine = fmt.Sprintf("%v.SetPointer(%v)", varGValue, varGoValue)
+
+	case gi.TYPE_TAG_INTERFACE:
+		bi := ti.Interface()
+		defer bi.Unref()
+		biType := bi.Type()
+
+		if isPtr {
+			switch biType {
+			case gi.INFO_TYPE_OBJECT, gi.INFO_TYPE_INTERFACE:
+				r.type0 = getTypeNameWithBaseInfo(bi)
+				r.inType0 = addPrefixIForType(r.type0)
+				// 和函数的参数一样，需要处理接口变量为 nil 的情况。
+				varTmp := varReg.alloc("tmp")
+				r.beforeSetLines = append(r.beforeSetLines,
+					fmt.Sprintf("var %v unsafe.Pointer", varTmp),
+					fmt.Sprintf("if %v != nil {", varGoValue),
+					fmt.Sprintf("%v = %v.P_%v()", varTmp, varGoValue, getGoTypeName(bi.Namespace(), bi.Name())),
+					"}", // end if
+				)
+				r.setLine = fmt.Sprintf("%v.SetPointer(%v)", varGValue, varTmp)
+
+			case gi.INFO_TYPE_STRUCT, gi.INFO_TYPE_UNION, gi.INFO_TYPE_BOXED:
+				r.type0 = getTypeNameWithBaseInfo(bi)
+				r.setLine = fmt.Sprintf("%v.SetPointer(%v.P)", varGValue, varGoValue)
+
+			default:
+				return nil
+			}
+			r.getLine = fmt.Sprintf("%v.P = %v.%v()", varGoValue, varGValue, getPointerMethod)
+
+		} else {
+			switch biType {
+			case gi.INFO_TYPE_ENUM:
+				r.type0 = getEnumTypeName(getTypeNameWithBaseInfo(bi))
+				r.getLine = fmt.Sprintf("%v = %v(%v.Int64())", varGoValue, r.type0, varGValue)
+				r.setLine = fmt.Sprintf("%v.SetInt64(int64(%v))", varGValue, varGoValue)
+
+			case gi.INFO_TYPE_FLAGS:
+				r.type0 = getFlagsTypeName(getTypeNameWithBaseInfo(bi))
+				r.getLine = fmt.Sprintf("%v = %v(%v.Uint64())", varGoValue, r.type0, varGValue)
+				r.setLine = fmt.Sprintf("%v.SetUint64(uint64(%v))", varGValue, varGoValue)
+
+			default:
+				return nil
+			}
+		}
+
+	default:
+		return nil
+	}
+
+	if r.inType0 == "" {
+		r.inType0 = r.type0
+	}
+	return &r
+}
+
+// getPropAccessorMethodName 返回 gir 中声明的属性 getter 或 setter 方法生成的 Go 方法名，
+// 只有该方法生成了并且签名和属性存取方法一致时才返回，否则返回空字符串。
+func getPropAccessorMethodName(container propContainer, fnCtxs []*pFuncContext, methodName string,
+	propTi *gi.TypeInfo, isGetter bool) string {
+
+	fi := container.FindMethod(methodName)
+	if fi == nil {
+		return ""
+	}
+	defer fi.Unref()
+
+	fiFlags := fi.Flags()
+	if fiFlags&gi.FUNCTION_IS_METHOD == 0 || fiFlags&gi.FUNCTION_THROWS != 0 {
+		return ""
+	}
+
+	retTi := fi.ReturnType()
+	defer retTi.Unref()
+
+	if isGetter {
+		if fi.NumArg() != 0 || !isPropTypeSame(propTi, retTi) {
+			return ""
+		}
+	} else {
+		if fi.NumArg() != 1 || retTi.Tag() != gi.TYPE_TAG_VOID {
+			return ""
+		}
+		argInfo := fi.Arg(0)
+		argTi := argInfo.Type()
+		ok := argInfo.Direction() == gi.DIRECTION_IN && isPropTypeSame(propTi, argTi)
+		argTi.Unref()
+		argInfo.Unref()
+		if !ok {
+			return ""
+		}
+	}
+
+	// 被拒绝的、晚于最低版本引入的和不能正确处理的方法没有生成或者只生成了桩函数，不能调用。
+	// 方法本身没有版本信息时，它的版本回退到所属类型的版本，属性的版本可能没有这样回退。
+	ctx := findMethodCtx(fnCtxs, methodName)
+	if ctx == nil || ctx.isOmitted() {
+		return ""
+	}
+	return ctx.fnName
+}
+
+// findMethodCtx 返回 fnCtxs 中为 gir 中名为 name 的方法生成 Go 方法的 pFuncContext，找不到返回 nil。
+func findMethodCtx(fnCtxs []*pFuncContext, name string) *pFuncContext {
+	for _, ctx := range fnCtxs {
+		// 异步函数的包装函数的 fi 为 nil
+		if ctx.fi != nil && ctx.receiver != "" && ctx.name == name {
+			return ctx
+		}
+	}
+	return nil
+}
+
+// isPropTypeSame 判断方法的参数或返回值的类型 ti 生成的 Go 类型是否和属性的类型 propTi 一致。
+func isPropTypeSame(propTi, ti *gi.TypeInfo) bool {
+	tag := propTi.Tag()
+	if tag != ti.Tag() || propTi.IsPointer() != ti.IsPointer() {
+		return false
+	}
+	switch tag {
+	case gi.TYPE_TAG_UTF8, gi.TYPE_TAG_FILENAME,
+		gi.TYPE_TAG_BOOLEAN,
+		gi.TYPE_TAG_INT8, gi.TYPE_TAG_UINT8,
+		gi.TYPE_TAG_INT16, gi.TYPE_TAG_UINT16,
+		gi.TYPE_TAG_INT32, gi.TYPE_TAG_UINT32,
+		gi.TYPE_TAG_INT64, gi.TYPE_TAG_UINT64,
+		gi.TYPE_TAG_FLOAT, gi.TYPE_TAG_DOUBLE,
+		gi.TYPE_TAG_UNICHAR:
+		return true
+
+	case gi.TYPE_TAG_INTERFACE:
+		propBi := propTi.Interface()
+		bi := ti.Interface()
+		same := propBi.Namespace() == bi.Namespace() && propBi.Name() == bi.Name()
+		propBi.Unref()
+		bi.Unref()
+		return same
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/electricface/go-gir3/gi"
+)
+
+// todoReport 是一个命名空间中生成了 TODO 代码或者不支持的函数、字段、回调、信号和虚函数的报告，
+// 用于安排生成器的工作和比较不同版本的库。
+type todoReport struct {
+	Namespace   string
+	Version     string
+	NumFunc     int // 函数总数
+	NumTodoFunc int // 生成了 TODO 代码的函数个数
+	Items       []*todoItem
+}
+
+// todoItem 是报告中的一项
+type todoItem struct {
+	Kind    string       // function, field, callback, signal 或 vfunc
+	Name    string       // GIR 中的名字，比如 Widget.show，Rectangle.x，Widget.size-allocate
+	Symbol  string       `json:",omitempty"` // 函数的 C 符号
+	Reasons []todoReason `json:",omitempty"`
+	// 生成的代码中的 TODO 注释，比如 "TODO_TYPE isPtr: true, tag: ghash"
+	Details []string `json:",omitempty"`
+}
+
+// todoReason 是导致生成 TODO 代码的参数、返回值或字段
+type todoReason struct {
+	Arg       string // 参数名，返回值是 "return"，字段是字段名
+	Direction string `json:",omitempty"`
+	Transfer  string `json:",omitempty"`
+	Tag       string
+	IsPointer bool
+	Interface string `json:",omitempty"` // tag 为 interface 时是类型的种类和名字，比如 "struct GLib.Array"
+	ElemTag   string `json:",omitempty"` // tag 为 array, glist, gslist 时是元素类型的 tag
+}
+
+var _todoReport = &todoReport{}
+
+// 键是 kind 和 name
+var _todoItemMap = make(map[string]*todoItem)
+
+// addTodoItem 在报告中加入一项，同一个东西只加入一次，后加入的原因和细节会合并到已有的项中。
+func addTodoItem(item *todoItem) {
+	key := item.Kind + " " + item.Name
+	if exist, ok := _todoItemMap[key]; ok {
+		for _, reason := range item.Reasons {
+			if !containsTodoReason(exist.Reasons, reason) {
+				exist.Reasons = append(exist.Reasons, reason)
+			}
+		}
+		for _, detail := range item.Details {
+			if !strSliceContains(exist.Details, detail) {
+				exist.Details = append(exist.Details, detail)
+			}
+		}
+		return
+	}
+	_todoItemMap[key] = item
+	_todoReport.Items = append(_todoReport.Items, item)
+}
+
+func containsTodoReason(reasons []todoReason, reason todoReason) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// newTypeTodoReason 根据类型 ti 创建原因，arg 是参数名或字段名。
+func newTypeTodoReason(arg string, ti *gi.TypeInfo) todoReason {
+	tag := ti.Tag()
+	reason := todoReason{
+		Arg:       arg,
+		Tag:       tag.String(),
+		IsPointer: ti.IsPointer(),
+	}
+	switch tag {
+	case gi.TYPE_TAG_INTERFACE:
+		bi := ti.Interface()
+		if bi != nil {
+			reason.Interface = bi.Type().String() + " " + bi.Namespace() + "." + bi.Name()
+			bi.Unref()
+		}
+	case gi.TYPE_TAG_ARRAY, gi.TYPE_TAG_GLIST, gi.TYPE_TAG_GSLIST:
+		elemTi := ti.ParamType(0)
+		if elemTi != nil {
+			reason.ElemTag = elemTi.Tag().String()
+			elemTi.Unref()
+		}
+	}
+	return reason
+}
+
+// newArgTodoReason 根据参数 ai 创建原因
+func newArgTodoReason(ai *gi.ArgInfo) todoReason {
+	ti := ai.Type()
+	defer ti.Unref()
+	reason := newTypeTodoReason(ai.Name(), ti)
+	reason.Direction = ai.Direction().String()
+	reason.Transfer = ai.OwnershipTransfer().String()
+	return reason
+}
+
+// newRetTodoReason 根据可调用对象 ci 的返回值创建原因
+func newRetTodoReason(ci *gi.CallableInfo) todoReason {
+	ti := ci.ReturnType()
+	defer ti.Unref()
+	reason := newTypeTodoReason("return", ti)
+	reason.Direction = gi.DIRECTION_OUT.String()
+	reason.Transfer = ci.CallerOwns().String()
+	return reason
+}
+
+var todoCommentReg = regexp.MustCompile(`/\*\s*(TODO[^*]*?)\s*\*/`)
+
+// getTodoDetails 返回代码 code 中的 TODO 注释的内容，去重并保持顺序。
+func getTodoDetails(code string) []string {
+	var result []string
+	for _, match := range todoCommentReg.FindAllStringSubmatch(code, -1) {
+		if !strSliceContains(result, match[1]) {
+			result = append(result, match[1])
+		}
+	}
+	return result
+}
+
+// countTodo 返回函数上下文中已经生成的代码中 TODO 的个数，用于找出导致生成 TODO 代码的参数。
+func (ctx *pFuncContext) countTodo() int {
+	n := 0
+	lists := [][]string{ctx.params, ctx.retParams, ctx.beforeNewArgLines, ctx.newArgLines, ctx.argNames,
+		ctx.afterCallLines, ctx.setParamLines, ctx.beforeRetLines}
+	for _, list := range lists {
+		for _, line := range list {
+			n += strings.Count(line, "TODO")
+		}
+	}
+	return n
+}
+
+// saveTodoReport 保存报告，jsonFile 和 htmlFile 为空则不保存对应格式的报告。
+func saveTodoReport(jsonFile, htmlFile string) error {
+	r := _todoReport
+	r.Namespace = _optNamespace
+	r.Version = _optVersion
+	r.NumFunc = _numFunc
+	r.NumTodoFunc = _numTodoFunc
+	sort.SliceStable(r.Items, func(i, j int) bool {
+		a, b := r.Items[i], r.Items[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+
+	if jsonFile != "" {
+		data, err := json.MarshalIndent(r, "", "\t")
+		if err != nil {
+			return err
+		}
+		err = writeReportFile(jsonFile, append(data, '\n'))
+		if err != nil {
+			return err
+		}
+	}
+
+	if htmlFile != "" {
+		var buf strings.Builder
+		err := todoReportTemplate.Execute(&buf, r)
+		if err != nil {
+			return err
+		}
+		err = writeReportFile(htmlFile, []byte(buf.String()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeReportFile(filename string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+var todoReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Namespace}}-{{.Version}} TODO report</title>
+<style>
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>{{.Namespace}}-{{.Version}}</h1>
+<p>TODO/ALL functions: {{.NumTodoFunc}}/{{.NumFunc}}, items: {{len .Items}}</p>
+<table>
+<tr><th>Kind</th><th>Name</th><th>Symbol</th><th>Reasons</th><th>Details</th></tr>
+{{range .Items}}<tr>
+<td>{{.Kind}}</td>
+<td>{{.Name}}</td>
+<td>{{.Symbol}}</td>
+<td>{{range .Reasons}}<div>{{.Arg}}:{{with .Direction}} dir={{.}}{{end}}{{with .Transfer}} transfer={{.}}{{end}} tag={{.Tag}}{{if .IsPointer}}*{{end}}{{with .Interface}} ({{.}}){{end}}{{with .ElemTag}} elem={{.}}{{end}}</div>{{end}}</td>
+<td>{{range .Details}}<div>{{.}}</div>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// addFieldTodoItem 在报告中加入类型为 ti 的字段 fi
+func addFieldTodoItem(fi *gi.FieldInfo, ti *gi.TypeInfo) {
+	name := fi.Name()
+	// NOTE: 不要调用 container 的 Unref 方法，Container() 没有转移所有权。
+	container := fi.Container()
+	if container != nil {
+		name = container.Name() + "." + name
+	}
+	addTodoItem(&todoItem{Kind: "field", Name: name, Reasons: []todoReason{newTypeTodoReason(fi.Name(), ti)}})
+}
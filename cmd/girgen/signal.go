@@ -0,0 +1,188 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/electricface/go-gir3/cmd/girgen/xmlp"
+	"github.com/electricface/go-gir3/gi"
+)
+
+/*
+打印信号的类型化连接方法，比如 Gtk.Button 的 clicked 信号的：
+
+	func (v Button) ConnectClicked(fn func(self Button)) g.SignalHandle {
+		return v.connectClicked(false, fn)
+	}
+
+	func (v Button) ConnectAfterClicked(fn func(self Button)) g.SignalHandle {
+		return v.connectClicked(true, fn)
+	}
+
+	func (v Button) connectClicked(after bool, fn func(self Button)) g.SignalHandle {
+		marshal := func(params []gi.Value, ret gi.Value) {
+			var self Button
+			self.P = params[0].PeekPointer()
+			fn(self)
+		}
+		id := gi.SignalConnect(v.P, "clicked", after, marshal)
+		return g.SignalHandle(id)
+	}
+*/
+func pSignalConnect(s *SourceFile, container propContainer, isIfc bool, si *gi.SignalInfo) {
+	sigName := si.Name()
+	name := toCamelCase(sigName, "-")
+	identifyName := container.Name() + "." + sigName
+
+	xSignal := _xRepo.GetSignal(container.Name(), sigName)
+	if xSignal != nil && isVersionTooNew(xSignal.Version) {
+		s.GoBody.Pn("// omitted signal %v, since %v is newer than min version %v\n",
+			identifyName, xSignal.Version, _cfg.MinVersion)
+		return
+	}
+
+	// 避免和 connect_xxx 或 connect_after_xxx 方法重名
+	methodSuffix := strings.Replace(sigName, "-", "_", -1)
+	for _, methodName := range []string{"connect_" + methodSuffix, "connect_after_" + methodSuffix} {
+		fi := container.FindMethod(methodName)
+		if fi != nil {
+			fi.Unref()
+			s.GoBody.Pn("// signal %v clashes with method %v\n", identifyName, methodName)
+			return
+		}
+	}
+
+	var varReg VarReg
+	varV := varReg.alloc("v")
+	varAfter := varReg.alloc("after")
+	varFn := varReg.alloc("fn")
+	varMarshal := varReg.alloc("marshal")
+	varParams := varReg.alloc("params")
+	varRet := varReg.alloc("ret")
+	varId := varReg.alloc("id")
+	varSelf := varReg.alloc("self")
+
+	selfType := getTypeName(container.Name())
+	receiverType := selfType
+	getPtrExpr := varV + ".P"
+	if isIfc {
+		receiverType = "*" + selfType + "Ifc"
+		getPtrExpr = fmt.Sprintf("*(*unsafe.Pointer)(unsafe.Pointer(%v))", varV)
+	}
+
+	// fn 的参数列表，元素是 "名字 类型"
+	fnParams := []string{varSelf + " " + selfType}
+	// 调用 fn 时的参数
+	fnArgs := []string{varSelf}
+	marshalLines := []string{
+		fmt.Sprintf("var %v %v", varSelf, selfType),
+		fmt.Sprintf("%v.P = %v[0].PeekPointer()", varSelf, varParams),
+	}
+
+	numArg := si.NumArg()
+	for i := 0; i < numArg; i++ {
+		argInfo := si.Arg(i)
+		paramName := varReg.registerParam(i, argInfo.Name())
+		ti := argInfo.Type()
+		var parseResult *parseGValueTypeResult
+		if argInfo.Direction() == gi.DIRECTION_IN {
+			parseResult = parseGValueType(ti, fmt.Sprintf("%v[%v]", varParams, i+1), paramName,
+				&varReg, false)
+		}
+		if parseResult == nil {
+			addTodoItem(&todoItem{Kind: "signal", Name: identifyName,
+				Reasons: []todoReason{newArgTodoReason(argInfo)}})
+		}
+		ti.Unref()
+		argInfo.Unref()
+
+		if parseResult == nil {
+			s.GoBody.Pn("// unsupported signal %v\n", identifyName)
+			return
+		}
+		fnParams = append(fnParams, paramName+" "+parseResult.type0)
+		fnArgs = append(fnArgs, paramName)
+		marshalLines = append(marshalLines, fmt.Sprintf("var %v %v", paramName, parseResult.type0),
+			parseResult.getLine)
+	}
+
+	fnCall := fmt.Sprintf("%v(%v)", varFn, strings.Join(fnArgs, ", "))
+	var fnRetType string
+	retTi := si.ReturnType()
+	if retTi.Tag() == gi.TYPE_TAG_VOID && !retTi.IsPointer() {
+		marshalLines = append(marshalLines, fnCall)
+	} else {
+		varResult := varReg.alloc("result")
+		parseResult := parseGValueType(retTi, varRet, varResult, &varReg, false)
+		if parseResult == nil {
+			addTodoItem(&todoItem{Kind: "signal", Name: identifyName,
+				Reasons: []todoReason{newRetTodoReason(&si.CallableInfo)}})
+			retTi.Unref()
+			s.GoBody.Pn("// unsupported signal %v\n", identifyName)
+			return
+		}
+		fnRetType = " " + parseResult.inType0
+		marshalLines = append(marshalLines, fmt.Sprintf("%v := %v", varResult, fnCall))
+		marshalLines = append(marshalLines, parseResult.beforeSetLines...)
+		marshalLines = append(marshalLines, parseResult.setLine)
+	}
+	retTi.Unref()
+
+	fnType := fmt.Sprintf("func(%v)%v", strings.Join(fnParams, ", "), fnRetType)
+	handleType := getGLibType("SignalHandle")
+	connectName := "connect" + name
+
+	if si.IsDeprecated() {
+		markDeprecated(s)
+	}
+	s.GoBody.Pn("// Connect%v 连接信号 %q 的处理函数 %v", name, sigName, varFn)
+	var sigDoc *xmlp.Documentation
+	if xSignal != nil {
+		sigDoc = &xSignal.Documentation
+		pDocParagraph(s.GoBody, sigDoc)
+	}
+	s.GoBody.Pn("func (%v %v) Connect%v(%v %v) %v {", varV, receiverType, name, varFn, fnType, handleType)
+	s.GoBody.Pn("return %v.%v(false, %v)", varV, connectName, varFn)
+	s.GoBody.Pn("}") // end func
+
+	if si.IsDeprecated() {
+		markDeprecated(s)
+	}
+	s.GoBody.Pn("// ConnectAfter%v 连接信号 %q 的处理函数 %v，它在默认处理函数之后被调用", name, sigName, varFn)
+	pDocParagraph(s.GoBody, sigDoc)
+	s.GoBody.Pn("func (%v %v) ConnectAfter%v(%v %v) %v {", varV, receiverType, name, varFn, fnType, handleType)
+	s.GoBody.Pn("return %v.%v(true, %v)", varV, connectName, varFn)
+	s.GoBody.Pn("}") // end func
+
+	s.GoBody.Pn("func (%v %v) %v(%v bool, %v %v) %v {", varV, receiverType, connectName, varAfter, varFn,
+		fnType, handleType)
+	s.GoBody.Pn("%v := func(%v []gi.Value, %v gi.Value) {", varMarshal, varParams, varRet)
+	for _, line := range marshalLines {
+		s.GoBody.Pn("%v", line)
+	}
+	s.GoBody.Pn("}") // end marshal func
+	s.GoBody.Pn("%v := gi.SignalConnect(%v, %q, %v, %v)", varId, getPtrExpr, sigName, varAfter, varMarshal)
+	s.GoBody.Pn("return %v(%v)", handleType, varId)
+	s.GoBody.Pn("}") // end func
+}
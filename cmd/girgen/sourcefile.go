@@ -25,9 +25,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -99,6 +101,61 @@ func (s *SourceFile) Save(filename string) error {
 	return nil
 }
 
+// CheckNameCollisions 在保存为 outFile 之前检查生成的代码中的名字冲突，包括和同一目录中手写的文件的冲突，
+// 并打印所有冲突。手写的文件是 lib.in 中同步过来的，不以 _auto.go 和 _test.go 结尾的 .go 文件。
+func (s *SourceFile) CheckNameCollisions(outFile string) error {
+	var buf bytes.Buffer
+	err := s.writeTo(&buf)
+	if err != nil {
+		return err
+	}
+	otherFiles, err := readHandwrittenFiles(filepath.Dir(outFile))
+	if err != nil {
+		return err
+	}
+	for _, warning := range getDeniedFuncsRenameWarnings(_cfg) {
+		log.Println("WARN:", warning)
+	}
+	collisions, err := checkNameCollisions(buf.Bytes(), otherFiles)
+	if err != nil {
+		// 语法错误留给 go fmt 报告
+		log.Println("WARN: failed to check name collisions:", err)
+		return nil
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+	for _, collision := range collisions {
+		log.Println("name collision:", collision)
+	}
+	return xerrors.Errorf("found %d name collisions, fix them with Renames in config", len(collisions))
+}
+
+// readHandwrittenFiles 读取目录 dir 中手写的 .go 文件，返回的 map 的键是文件名。
+func readHandwrittenFiles(dir string) (map[string][]byte, error) {
+	fileInfoList, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, xerrors.Errorf("read dir: %w", err)
+	}
+	files := make(map[string][]byte)
+	for _, info := range fileInfoList {
+		name := info.Name()
+		if info.IsDir() || filepath.Ext(name) != ".go" ||
+			strings.HasSuffix(name, "_auto.go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, xerrors.Errorf("read file: %w", err)
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
 func (s *SourceFile) writeTo(w io.Writer) error {
 	_, err := w.Write(s.Header.buf.Bytes())
 	if err != nil {
@@ -216,8 +273,7 @@ func (s *SourceFile) AddGoImport(imp string) {
 }
 
 func (s *SourceFile) AddGirImport(name string) {
-	fullPath := _girPkgPath + "/" + name
-	s.AddGoImport(fullPath)
+	s.AddGoImport(getGirImportPath(name))
 }
 
 type SourceBody struct {
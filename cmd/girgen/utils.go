@@ -24,14 +24,18 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode"
 
+	"github.com/electricface/go-gir3/cmd/girgen/xmlp"
 	"github.com/electricface/go-gir3/gi"
 	"golang.org/x/xerrors"
 )
@@ -94,7 +98,7 @@ func snake2Camel(name string) string {
 		out.WriteString(strings.ToUpper(word[0:1]))
 		out.WriteString(word[1:])
 	}
-	return out.String()
+	return applyInitialisms(out.String())
 }
 
 func toCamelCase(name, sep string) string {
@@ -107,7 +111,7 @@ func toCamelCase(name, sep string) string {
 		out.WriteString(strings.ToUpper(word[0:1]))
 		out.WriteString(word[1:])
 	}
-	return out.String()
+	return applyInitialisms(out.String())
 }
 
 var _keywords = append([]string{
@@ -288,7 +292,7 @@ func syncFilesToLibIn(libInDir, outDir string) error {
 		return xerrors.Errorf("read out dir: %w", err)
 	}
 
-	// 复制 go-gir 文件夹下所有 .go 但是不是 _auto.go 的，所有 *config.json。
+	// 复制 go-gir 文件夹下所有 .go 但是不是 _auto.go 的，所有 *config.json 和 *.metadata。
 
 	// 要复制的文件名列表
 	var srcNames []string
@@ -297,7 +301,7 @@ func syncFilesToLibIn(libInDir, outDir string) error {
 		name := info.Name()
 		ext := filepath.Ext(name)
 		if (ext == ".go" && !strings.HasSuffix(name, "_auto.go")) ||
-			strings.HasSuffix(name, "config.json") {
+			strings.HasSuffix(name, "config.json") || ext == ".metadata" {
 
 			srcNames = append(srcNames, name)
 		}
@@ -316,7 +320,10 @@ func syncFilesToLibIn(libInDir, outDir string) error {
 		src := filepath.Join(outDir, name)
 		dst := filepath.Join(libInDir, name)
 		log.Printf("copy %s -> %s\n", src, dst)
-		err := copyFileContent(src, dst)
+		// lib.in 中的文件总是使用 libInGirPkgPath 导入生成的包
+		err := copyGoFile(src, dst, func(importPath string) string {
+			return replacePathPrefix(importPath, _girPkgPath, libInGirPkgPath)
+		})
 		if err != nil {
 			return xerrors.Errorf("copy file content from %q to %q: %w", src, dst, err)
 		}
@@ -347,15 +354,22 @@ func syncFilesToOut(libInDir, outDir string) error {
 		for _, info := range fileInfoList {
 			name := info.Name()
 			ext := filepath.Ext(name)
-			if ext != ".go" && ext != ".json" {
-				// 只复制 .go 和 .json 文件
+			if ext != ".go" && ext != ".json" && ext != ".metadata" {
+				// 只复制 .go, .json 和 .metadata 文件
 				continue
 			}
 
 			src := filepath.Join(libInDir, name)
 			dst := filepath.Join(outDir, name)
 			log.Printf("copy %s -> %s\n", src, dst)
-			err = copyFileContent(src, dst)
+			// 和生成的文件一样用 getGirImportPath 得到导入路径，使用 -module-path 等指定的模块路径时也能编译。
+			err = copyGoFile(src, dst, func(importPath string) string {
+				dir := strings.TrimPrefix(importPath, libInGirPkgPath+"/")
+				if dir == importPath {
+					return importPath
+				}
+				return getGirImportPath(dir)
+			})
 			if err != nil {
 				return xerrors.Errorf("copy file content from %q to %q: %w", src, dst, err)
 			}
@@ -364,10 +378,62 @@ func syncFilesToOut(libInDir, outDir string) error {
 	return nil
 }
 
+// replacePathPrefix 把导入路径 importPath 的前缀 from 改为 to，importPath 不在 from 下时返回 importPath。
+func replacePathPrefix(importPath, from, to string) string {
+	if strings.HasPrefix(importPath, from+"/") {
+		return to + importPath[len(from):]
+	}
+	return importPath
+}
+
+// copyGoFile 复制文件 src 到 dst，如果是 .go 文件，用 mapImport 修改其中的导入路径，其它文件原样复制。
+func copyGoFile(src, dst string, mapImport func(importPath string) string) error {
+	if filepath.Ext(src) != ".go" {
+		return copyFileContent(src, dst)
+	}
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	content, err = rewriteImports(content, mapImport)
+	if err != nil {
+		return xerrors.Errorf("rewrite imports of %q: %w", src, err)
+	}
+	return ioutil.WriteFile(dst, content, 0644)
+}
+
+// rewriteImports 用 mapImport 修改 Go 源文件 src 中的导入路径，其它内容保持不变。
+func rewriteImports(src []byte, mapImport func(importPath string) string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	last := 0
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		newPath := mapImport(importPath)
+		if newPath == importPath {
+			continue
+		}
+		start := fset.Position(spec.Path.Pos()).Offset
+		end := fset.Position(spec.Path.End()).Offset
+		buf.Write(src[last:start])
+		buf.WriteString(strconv.Quote(newPath))
+		last = end
+	}
+	buf.Write(src[last:])
+	return buf.Bytes(), nil
+}
+
 // flag --sync-gi
 func syncLibGiToOut() error {
-	gopath := getGoPath()
-	outDir := filepath.Join(gopath, "src", _girPkgPath, "gi")
+	outDir := filepath.Join(getPkgRootDir(), "gi")
 	err := cleanFiles(outDir)
 	if err != nil {
 		return xerrors.Errorf("clean files: %w", err)
@@ -389,3 +455,61 @@ func syncLibGiToOut() error {
 	}
 	return nil
 }
+
+// compareVersion 比较 "2.66"、"3.24.1" 这样的版本号，v1 比 v2 小返回 -1，相等返回 0，大返回 1。
+// 缺少的部分当作 0，不是数字的部分也当作 0。
+func compareVersion(v1, v2 string) int {
+	parts1 := strings.Split(v1, ".")
+	parts2 := strings.Split(v2, ".")
+	for i := 0; i < len(parts1) || i < len(parts2); i++ {
+		var n1, n2 int
+		if i < len(parts1) {
+			n1, _ = strconv.Atoi(parts1[i])
+		}
+		if i < len(parts2) {
+			n2, _ = strconv.Atoi(parts2[i])
+		}
+		if n1 < n2 {
+			return -1
+		} else if n1 > n2 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// isVersionTooNew 返回在 version 版本引入的 API 是否晚于配置的最低版本 MinVersion，这种 API 不生成。
+func isVersionTooNew(version string) bool {
+	if version == "" || _cfg.MinVersion == "" {
+		return false
+	}
+	return compareVersion(version, _cfg.MinVersion) > 0
+}
+
+// pathListFlag 是可以重复指定的目录列表参数，每次的值也可以是用 os.PathListSeparator 分隔的多个目录。
+type pathListFlag []string
+
+func (f *pathListFlag) String() string {
+	return strings.Join(*f, string(os.PathListSeparator))
+}
+
+func (f *pathListFlag) Set(value string) error {
+	for _, dir := range filepath.SplitList(value) {
+		if dir != "" {
+			*f = append(*f, dir)
+		}
+	}
+	return nil
+}
+
+// setupSearchPath 设置 .gir 和 .typelib 文件的搜索路径，命令行参数中的目录在前面，先指定的优先。
+// .gir 文件还会在环境变量 XDG_DATA_DIRS 中每个目录下的 gir-1.0 目录中搜索，
+// .typelib 文件还会在环境变量 GI_TYPELIB_PATH 中的目录中搜索，这由 libgirepository 处理。
+func setupSearchPath() {
+	for i := len(_optGirDirs) - 1; i >= 0; i-- {
+		xmlp.PrependSearchPath(_optGirDirs[i])
+	}
+	for i := len(_optTypelibDirs) - 1; i >= 0; i-- {
+		gi.PreprendRepositorySearchPath(_optTypelibDirs[i])
+	}
+}
@@ -0,0 +1,342 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/electricface/go-gir3/gi"
+)
+
+/*
+打印覆盖对象虚函数用的接口和函数，比如 Gtk.Button 的：
+
+	type ButtonVFuncClicked interface {
+		VFuncClicked(parent gi.VFuncParent)
+	}
+
+	func OverrideButtonVFuncs(class unsafe.Pointer, impl interface{}) {
+		OverrideBinVFuncs(class, impl)
+		if _, ok := impl.(ButtonVFuncClicked); ok {
+			gi.OverrideVFunc(class, "Gtk", "Button", "clicked", func(result unsafe.Pointer, args []unsafe.Pointer) {
+				parent := gi.NewVFuncParent(class, "Gtk", "Button", "clicked", result, args)
+				self, ok := gi.GetClassInstanceData(class, *(*unsafe.Pointer)(args[0])).(ButtonVFuncClicked)
+				if !ok {
+					parent.Call()
+					return
+				}
+				self.VFuncClicked(parent)
+			})
+		}
+	}
+
+VFuncXxx 方法在 class 所属的类型为实例创建的 Go 值上调用，所以在 Go 中定义的类型再被 Go 中定义的类型派生时，
+parent.Call() 会调用到祖先类型的 VFuncXxx 方法。VFuncXxx 方法的第一个参数 parent 用于调用父类型的实现，
+Go 值没有实现 VFuncXxx 方法时也调用父类型的实现。
+*/
+func pObjectVFuncs(s *SourceFile, oi *gi.ObjectInfo) {
+	name := oi.Name()
+	typeName := getTypeName(name)
+
+	var varReg VarReg
+	varClass := varReg.alloc("class")
+	varImpl := varReg.alloc("impl")
+	varOk := varReg.alloc("ok")
+
+	var overrideLines []string
+	numVFunc := oi.NumVFunc()
+	for i := 0; i < numVFunc; i++ {
+		vfi := oi.VFunc(i)
+		lines := pVFunc(s, name, vfi, "gi.OverrideVFunc", "gi.NewVFuncParent", "gi.GetClassInstanceData",
+			varClass, varImpl, varOk)
+		overrideLines = append(overrideLines, lines...)
+		vfi.Unref()
+	}
+
+	s.GoBody.Pn("// Override%vVFuncs 用 %v 实现的 VFuncXxx 方法覆盖类结构体 %v 中 %v 及其祖先类型的虚函数，",
+		typeName, varImpl, varClass, typeName)
+	s.GoBody.Pn("// 它应该在 gi.SubclassInfo 的 ClassInit 中被调用。")
+	s.GoBody.Pn("func Override%vVFuncs(%v unsafe.Pointer, %v interface{}) {", typeName, varClass, varImpl)
+	parent := oi.Parent()
+	if parent != nil {
+		s.GoBody.Pn("%vOverride%vVFuncs(%v, %v)", getPkgPrefix(parent.Namespace()),
+			getGoTypeName(parent.Namespace(), parent.Name()), varClass, varImpl)
+		parent.Unref()
+	}
+	for _, line := range overrideLines {
+		s.GoBody.Pn("%v", line)
+	}
+	s.GoBody.Pn("}") // end func
+}
+
+/*
+打印在 Go 中实现接口用的接口和函数，比如 Gio.ListModel 的：
+
+	type ListModelVFuncGetNItems interface {
+		VFuncGetNItems(parent gi.VFuncParent) (result uint32)
+	}
+
+	func ImplementListModel(iface unsafe.Pointer, impl interface{}) {
+		if _, ok := impl.(ListModelVFuncGetNItems); ok {
+			gi.OverrideInterfaceVFunc(iface, "Gio", "ListModel", "get_n_items", func(result unsafe.Pointer, args []unsafe.Pointer) {
+				...
+			})
+		}
+	}
+*/
+func pInterfaceVFuncs(s *SourceFile, ii *gi.InterfaceInfo) {
+	name := ii.Name()
+	typeName := getTypeName(name)
+
+	var varReg VarReg
+	varIface := varReg.alloc("iface")
+	varImpl := varReg.alloc("impl")
+	varOk := varReg.alloc("ok")
+
+	var implementLines []string
+	numVFunc := ii.NumVFunc()
+	for i := 0; i < numVFunc; i++ {
+		vfi := ii.VFunc(i)
+		lines := pVFunc(s, name, vfi, "gi.OverrideInterfaceVFunc", "gi.NewInterfaceVFuncParent",
+			"gi.GetInterfaceInstanceData", varIface, varImpl, varOk)
+		implementLines = append(implementLines, lines...)
+		vfi.Unref()
+	}
+
+	s.GoBody.Pn("// Implement%v 用 %v 实现的 VFuncXxx 方法填充接口结构体 %v 中 %v 的虚函数，", typeName, varImpl,
+		varIface, typeName)
+	s.GoBody.Pn("// 它应该在 gi.SubclassInterface 的 Init 中被调用。")
+	s.GoBody.Pn("func Implement%v(%v unsafe.Pointer, %v interface{}) {", typeName, varIface, varImpl)
+	for _, line := range implementLines {
+		s.GoBody.Pn("%v", line)
+	}
+	s.GoBody.Pn("}") // end func
+}
+
+// pVFunc 打印虚函数 vfi 对应的接口类型，返回在 OverrideXxxVFuncs 或 ImplementXxx 函数中
+// 用 overrideFn 设置此虚函数的语句，parentFn 用于获取父类型的实现，instanceDataFn 用于获取填充此虚函数的类型
+// 为实例创建的 Go 值。不支持的虚函数返回 nil。
+func pVFunc(s *SourceFile, containerName string, vfi *gi.VFuncInfo, overrideFn, parentFn, instanceDataFn, varClass,
+	varImpl, varOk string) []string {
+	vfName := vfi.Name()
+	identifyName := containerName + "." + vfName
+
+	if vfi.Flags()&gi.VFUNC_MUST_NOT_OVERRIDE != 0 {
+		return nil
+	}
+	if _optNamespace == "GObject" && containerName == "Object" && vfName == "finalize" {
+		// finalize 用于解除 Go 值和实例的绑定，不能被覆盖。
+		return nil
+	}
+	if vfi.CanThrowGError() {
+		addTodoItem(&todoItem{Kind: "vfunc", Name: identifyName, Details: []string{"throws GError"}})
+		s.GoBody.Pn("// unsupported vfunc %v\n", identifyName)
+		return nil
+	}
+
+	varReg := VarReg{}
+	// 这些是 OverrideXxxVFuncs 或 ImplementXxx 函数中已经使用的变量名
+	varReg.alloc(varClass)
+	varReg.alloc(varImpl)
+	varReg.alloc(varOk)
+	varResult := varReg.alloc("result")
+	varArgs := varReg.alloc("args")
+	varSelf := varReg.alloc("self")
+	varParent := varReg.alloc("parent")
+
+	// 接口方法的参数和返回值列表，元素是 "名字 类型"
+	paramNameTypes := []string{varParent + " gi.VFuncParent"}
+	var retNameTypes []string
+	var beforeCallLines []string
+	var afterCallLines []string
+	var fnArgs []string
+	var fnRets []string
+
+	isTodo := func(goType string) bool {
+		return strings.Contains(goType, "TODO")
+	}
+
+	numArg := vfi.NumArg()
+	for i := 0; i < numArg; i++ {
+		argInfo := vfi.Arg(i)
+		argTypeInfo := argInfo.Type()
+		paramName := varReg.registerParam(i, argInfo.Name())
+		// args[0] 是实例
+		argI := fmt.Sprintf("%v[%v]", varArgs, i+1)
+		supported := true
+
+		switch argInfo.Direction() {
+		case gi.DIRECTION_IN:
+			result := parseCbArgTypeDirIn(paramName, argTypeInfo, argInfo, argI, i)
+			if result.isRet || isTodo(result.goType) {
+				supported = false
+			} else if result.goType != "" {
+				beforeCallLines = append(beforeCallLines, fmt.Sprintf("%v := %v", paramName, result.expr))
+				paramNameTypes = append(paramNameTypes, paramName+" "+result.goType)
+				fnArgs = append(fnArgs, paramName)
+			}
+
+		case gi.DIRECTION_OUT:
+			varFnRetOther := varReg.alloc("fn_ret_" + paramName)
+			result := parseCbArgTypeDirOut(paramName, argTypeInfo, argI, varFnRetOther)
+			if isTodo(result.goType) {
+				supported = false
+			} else {
+				beforeCallLines = append(beforeCallLines, fmt.Sprintf("%v := %v", paramName, result.expr))
+				retNameTypes = append(retNameTypes, paramName+" "+result.goType)
+				fnRets = append(fnRets, varFnRetOther)
+				afterCallLines = append(afterCallLines, fmt.Sprintf("if %v != nil {", paramName),
+					fmt.Sprintf("*%v = %v", paramName, result.assignExpr),
+					"}") // end if
+			}
+
+		default:
+			supported = false
+		}
+
+		if !supported {
+			addTodoItem(&todoItem{Kind: "vfunc", Name: identifyName,
+				Reasons: []todoReason{newArgTodoReason(argInfo)}})
+		}
+		argTypeInfo.Unref()
+		argInfo.Unref()
+		if !supported {
+			s.GoBody.Pn("// unsupported vfunc %v\n", identifyName)
+			return nil
+		}
+	}
+
+	retType := vfi.ReturnType()
+	varFnRet := varReg.alloc("fnRet")
+	retResult := parseCbRet(varResult, varFnRet, retType)
+	retType.Unref()
+	if isTodo(retResult.goType) {
+		addTodoItem(&todoItem{Kind: "vfunc", Name: identifyName,
+			Reasons: []todoReason{newRetTodoReason(&vfi.CallableInfo)},
+			Details: getTodoDetails(retResult.goType)})
+		s.GoBody.Pn("// unsupported vfunc %v\n", identifyName)
+		return nil
+	}
+	if retResult.goType != "" {
+		retNameTypes = append([]string{varResult + " " + retResult.goType}, retNameTypes...)
+		fnRets = append([]string{varFnRet}, fnRets...)
+		afterCallLines = append([]string{retResult.assignLine}, afterCallLines...)
+	}
+
+	methodName := "VFunc" + snake2Camel(vfName)
+	ifcName := getTypeName(containerName) + methodName
+	retPart := ""
+	if len(retNameTypes) > 0 {
+		retPart = "(" + strings.Join(retNameTypes, ", ") + ")"
+	}
+	s.GoBody.Pn("// %v 由覆盖虚函数 %v 的 Go 类型实现", ifcName, identifyName)
+	s.GoBody.Pn("type %v interface {", ifcName)
+	s.GoBody.Pn("%v(%v) %v", methodName, strings.Join(paramNameTypes, ", "), retPart)
+	s.GoBody.Pn("}") // end interface
+
+	o := &vfuncOverride{
+		overrideFn:      overrideFn,
+		parentFn:        parentFn,
+		instanceDataFn:  instanceDataFn,
+		namespace:       _optNamespace,
+		containerName:   containerName,
+		vfName:          vfName,
+		ifcName:         ifcName,
+		methodName:      methodName,
+		varClass:        varClass,
+		varImpl:         varImpl,
+		varOk:           varOk,
+		varResult:       varResult,
+		varArgs:         varArgs,
+		varSelf:         varSelf,
+		varParent:       varParent,
+		beforeCallLines: beforeCallLines,
+		afterCallLines:  afterCallLines,
+		fnArgs:          fnArgs,
+		fnRets:          fnRets,
+	}
+	return o.lines()
+}
+
+// vfuncOverride 包含生成设置一个虚函数的语句所需的信息
+type vfuncOverride struct {
+	overrideFn string // gi.OverrideVFunc 或 gi.OverrideInterfaceVFunc
+	parentFn   string // gi.NewVFuncParent 或 gi.NewInterfaceVFuncParent
+	// gi.GetClassInstanceData 或 gi.GetInterfaceInstanceData，用类结构体或接口结构体找到 Go 值，
+	// 而不是用 gi.GetInstanceData 找实例的最后代类型的 Go 值，否则 parent.Call() 会再次调用同一个 VFuncXxx 方法。
+	instanceDataFn string
+
+	namespace     string
+	containerName string
+	vfName        string
+	ifcName       string
+	methodName    string
+
+	varClass  string
+	varImpl   string
+	varOk     string
+	varResult string
+	varArgs   string
+	varSelf   string
+	varParent string
+
+	// 调用 VFuncXxx 方法前把 C 参数转换为 Go 值的语句
+	beforeCallLines []string
+	// 调用 VFuncXxx 方法后把 Go 返回值写入 C 的语句
+	afterCallLines []string
+	// VFuncXxx 方法除 parent 之外的参数
+	fnArgs []string
+	fnRets []string
+}
+
+// lines 返回设置虚函数的语句，Go 值没有实现 VFuncXxx 方法时调用父类型的实现。
+func (o *vfuncOverride) lines() []string {
+	callPrefix := ""
+	if len(o.fnRets) > 0 {
+		callPrefix = strings.Join(o.fnRets, ", ") + " := "
+	}
+	fnArgs := append([]string{o.varParent}, o.fnArgs...)
+
+	lines := []string{
+		fmt.Sprintf("if _, %v := %v.(%v); %v {", o.varOk, o.varImpl, o.ifcName, o.varOk),
+		fmt.Sprintf("%v(%v, %q, %q, %q, func(%v unsafe.Pointer, %v []unsafe.Pointer) {",
+			o.overrideFn, o.varClass, o.namespace, o.containerName, o.vfName, o.varResult, o.varArgs),
+		fmt.Sprintf("%v := %v(%v, %q, %q, %q, %v, %v)", o.varParent, o.parentFn, o.varClass,
+			o.namespace, o.containerName, o.vfName, o.varResult, o.varArgs),
+		fmt.Sprintf("%v, %v := %v(%v, *(*unsafe.Pointer)(%v[0])).(%v)",
+			o.varSelf, o.varOk, o.instanceDataFn, o.varClass, o.varArgs, o.ifcName),
+		fmt.Sprintf("if !%v {", o.varOk),
+		fmt.Sprintf("%v.Call()", o.varParent),
+		"return",
+		"}", // end if
+	}
+	lines = append(lines, o.beforeCallLines...)
+	lines = append(lines, fmt.Sprintf("%v%v.%v(%v)", callPrefix, o.varSelf, o.methodName,
+		strings.Join(fnArgs, ", ")))
+	lines = append(lines, o.afterCallLines...)
+	lines = append(lines,
+		"})", // end OverrideVFunc
+		"}",  // end if
+	)
+	return lines
+}
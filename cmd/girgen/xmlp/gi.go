@@ -26,6 +26,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -72,8 +73,6 @@ func (err *ParseCTypeError) Error() string {
 }
 
 func (r *Repository) postDecode() {
-	r.loadIncludeRepos()
-
 	var err error
 	r.typeMap = make(map[string]TypeDefine)
 
@@ -268,6 +267,64 @@ func (r *Repository) GetType(name string) (TypeDefine, string) {
 	return r.typeMap[name], r.Namespace.Name
 }
 
+// IncludeRepos 返回直接包含的仓库，键是命名空间。
+func (r *Repository) IncludeRepos() map[string]*Repository {
+	return r.includeRepos
+}
+
+// GetFunction 获取类型 containerName 中名为 name 的函数、构造器或方法，containerName 为空时获取命名空间中的函数，
+// 找不到返回 nil。
+func (r *Repository) GetFunction(containerName, name string) *FunctionInfo {
+	var lists [][]*FunctionInfo
+	if containerName == "" {
+		lists = append(lists, r.Namespace.Functions)
+	} else {
+		switch typeDef := r.typeMap[containerName].(type) {
+		case *StructInfo:
+			lists = append(lists, typeDef.Functions, typeDef.Constructors, typeDef.Methods)
+		case *ObjectInfo:
+			lists = append(lists, typeDef.Functions, typeDef.Constructors, typeDef.Methods)
+		case *InterfaceInfo:
+			lists = append(lists, typeDef.Functions, typeDef.Methods)
+		}
+	}
+	for _, list := range lists {
+		for _, fn := range list {
+			if fn.NameAttr == name {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+// GetSignal 获取对象或接口 containerName 的信号 name，找不到返回 nil。
+func (r *Repository) GetSignal(containerName, name string) *SignalInfo {
+	var signals []*SignalInfo
+	switch typeDef := r.typeMap[containerName].(type) {
+	case *ObjectInfo:
+		signals = typeDef.Signals
+	case *InterfaceInfo:
+		signals = typeDef.Signals
+	}
+	for _, signal := range signals {
+		if signal.NameAttr == name {
+			return signal
+		}
+	}
+	return nil
+}
+
+// GetConstant 根据名字获取本命名空间中的常量，找不到返回 nil。
+func (r *Repository) GetConstant(name string) *ConstantInfo {
+	for _, constant := range r.Namespace.Constants {
+		if constant.NameAttr == name {
+			return constant
+		}
+	}
+	return nil
+}
+
 func (r *Repository) GetTypes() map[string]TypeDefine {
 	return r.typeMap
 }
@@ -282,16 +339,18 @@ func (r *Repository) CIncludes() []*Include {
 	return ret
 }
 
-func (r *Repository) loadIncludeRepos() {
+// loadIncludeRepos 加载包含的仓库，.gir 文件的搜索路径与 Load 的相同。
+func (r *Repository) loadIncludeRepos() error {
 	r.includeRepos = make(map[string]*Repository)
 	for _, inc := range r.CoreIncludes() {
 		repo, err := Load(inc.Name, inc.Version)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("failed to load include %s-%s: %w", inc.Name, inc.Version, err)
 		}
 
 		r.includeRepos[inc.Name] = repo
 	}
+	return nil
 }
 
 func (r *Repository) CoreIncludes() []*Include {
@@ -334,11 +393,28 @@ type Namespace struct {
 }
 
 type BaseInfo struct {
-	NameAttr          string `xml:"name,attr"`
-	CTypeAttr         string `xml:"type,attr"` // c:type attr
+	Documentation
+	NameAttr  string `xml:"name,attr"`
+	CTypeAttr string `xml:"type,attr"` // c:type attr
+	cType     *CType
+}
+
+// Documentation 是 GIR 中元素的文档和版本信息
+type Documentation struct {
+	Doc               *Doc   `xml:"doc"`
+	DocDeprecated     *Doc   `xml:"doc-deprecated"`
+	Version           string `xml:"version,attr"`
 	Deprecated        bool   `xml:"deprecated,attr"`
 	DeprecatedVersion string `xml:"deprecated-version,attr"`
-	cType             *CType
+}
+
+// GetDocumentation 用于从各种元素中获取文档信息
+func (d *Documentation) GetDocumentation() *Documentation {
+	return d
+}
+
+type Doc struct {
+	Text string `xml:",chardata"`
 }
 
 func (b *BaseInfo) Name() string {
@@ -371,6 +447,8 @@ type FunctionInfo struct {
 	Introspectable bool        `xml:"introspectable,attr"`
 	Shadows        string      `xml:"shadows,attr"`
 	ShadowedBy     string      `xml:"shadowed-by,attr"`
+	// 异步函数对应的 finish 函数的名字，来自 glib:finish-func 属性，GIR 1.72 开始提供
+	FinishFunc string `xml:"finish-func,attr"`
 
 	container TypeDefine
 }
@@ -493,14 +571,18 @@ func (arr *ArrayType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error
 }
 
 type Property struct {
+	Documentation
 	Name              string     `xml:"name,attr"`
 	Writable          bool       `xml:"writable,attr"`
 	ConstructOnly     bool       `xml:"construct-only,attr"`
 	TransferOwnership string     `xml:"transfer-ownership,attr"`
+	Getter            string     `xml:"getter,attr"` // GIR 1.72 开始提供
+	Setter            string     `xml:"setter,attr"` // GIR 1.72 开始提供
 	Array             *ArrayType `xml:"array"`
 }
 
 type Field struct {
+	Documentation
 	Name     string        `xml:"name,attr"`
 	Readable bool          `xml:"readable,attr"`
 	Writable bool          `xml:"writable,attr"`
@@ -563,6 +645,7 @@ type EnumInfo struct {
 }
 
 type EnumMember struct {
+	Documentation
 	Name        string `xml:"name,attr"`
 	Value       string `xml:"value,attr"`
 	CIdentifier string `xml:"identifier,attr"`
@@ -608,7 +691,8 @@ type InterfaceInfo struct {
 	VirtualMethods []*VFuncInfo    `xml:"virtual-method"`
 	Methods        []*FunctionInfo `xml:"method"`
 
-	Properties []*Property `xml:"property"`
+	Properties []*Property   `xml:"property"`
+	Signals    []*SignalInfo `xml:"signal"`
 }
 
 func Load(namespace, version string) (*Repository, error) {
@@ -618,13 +702,16 @@ func Load(namespace, version string) (*Repository, error) {
 		return repo, nil
 	}
 
-	var err error
-	girFile := fmt.Sprintf("/usr/share/gir-1.0/%s-%s.gir", namespace, version)
+	girFile, err := findGirFile(namespace, version)
+	if err != nil {
+		return nil, err
+	}
 	fmt.Println("// load file:", girFile)
 	girFh, err := os.Open(girFile)
 	if err != nil {
 		return nil, err
 	}
+	defer girFh.Close()
 
 	var repo Repository
 	dec := xml.NewDecoder(bufio.NewReader(girFh))
@@ -632,8 +719,63 @@ func Load(namespace, version string) (*Repository, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = repo.loadIncludeRepos()
+	if err != nil {
+		return nil, err
+	}
 	repo.postDecode()
 	fmt.Println("// end load", namespace, version)
 	loadedRepos[nsVer] = &repo
 	return &repo, nil
 }
+
+// 由 PrependSearchPath 加入的目录，前面的优先
+var _searchPath []string
+
+// PrependSearchPath 把目录 dir 加入 .gir 文件的搜索路径的最前面，与 gi.PreprendRepositorySearchPath 对应。
+func PrependSearchPath(dir string) {
+	_searchPath = append([]string{dir}, _searchPath...)
+}
+
+// SearchPath 返回 .gir 文件的搜索路径，依次是 PrependSearchPath 加入的目录，
+// 环境变量 XDG_DATA_DIRS 中每个目录下的 gir-1.0 目录，最后是 /usr/share/gir-1.0。
+func SearchPath() []string {
+	result := append([]string{}, _searchPath...)
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		if dir == "" {
+			continue
+		}
+		result = append(result, filepath.Join(dir, "gir-1.0"))
+	}
+	result = append(result, "/usr/share/gir-1.0")
+
+	// 去重
+	seen := make(map[string]struct{}, len(result))
+	var paths []string
+	for _, dir := range result {
+		dir = filepath.Clean(dir)
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+		seen[dir] = struct{}{}
+		paths = append(paths, dir)
+	}
+	return paths
+}
+
+func findGirFile(namespace, version string) (string, error) {
+	name := namespace + "-" + version + ".gir"
+	searchPath := SearchPath()
+	for _, dir := range searchPath {
+		filename := filepath.Join(dir, name)
+		_, err := os.Stat(filename)
+		if err == nil {
+			return filename, nil
+		}
+	}
+	return "", fmt.Errorf("gir file %s not found in search path %v", name, searchPath)
+}
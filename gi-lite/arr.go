@@ -96,6 +96,9 @@ func (arr *CStrArray) FreeAll() {
 	if arr.Len < 0 {
 		panic("arr.len < 0")
 	}
+	if arr.P == nil {
+		return
+	}
 	slice := (*(*[arrLenMax]unsafe.Pointer)(arr.P))[:arr.Len:arr.Len]
 	for i := 0; i < arr.Len; i++ {
 		Free(slice[i])
@@ -138,6 +141,10 @@ func NewCStrArrayZTWithStrings(values ...string) CStrArray {
 }
 
 func (arr *CStrArray) SetLenZT() {
+	if arr.P == nil {
+		arr.Len = 0
+		return
+	}
 	slice := (*(*[arrLenMax]unsafe.Pointer)(arr.P))[:arrLenMax:arrLenMax]
 	for i, value := range slice {
 		if value == nil {
@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+/*
+#include <glib.h>
+#cgo pkg-config: glib-2.0
+*/
+import "C"
+import "runtime"
+
+// Await 用于把 GIO 的异步操作变成同步调用。
+// 它会创建一个新的 main context，并把它设置为当前线程的 thread-default main context，再调用 start，
+// 然后迭代这个 main context，直到传给 start 的 done 函数被调用。
+// 异步操作的回调总是在发起操作时线程的 thread-default main context 中执行，所以回调会在当前线程中
+// 被执行，而不依赖于其他线程是否在运行 main loop，也不会在其他 main context 中被执行。
+func Await(start func(done func())) {
+	// thread-default main context 是线程相关的，迭代期间不能切换线程。
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	ctx := C.g_main_context_new()
+	C.g_main_context_push_thread_default(ctx)
+	defer func() {
+		C.g_main_context_pop_thread_default(ctx)
+		C.g_main_context_unref(ctx)
+	}()
+
+	// 回调在 g_main_context_iteration 中被调用，和这里处在同一个线程，所以不需要加锁。
+	finished := false
+	start(func() {
+		finished = true
+	})
+	for !finished {
+		C.g_main_context_iteration(ctx, C.TRUE)
+	}
+}
@@ -67,7 +67,6 @@ static void call_my_destroy_fn(void* ptr) {
 */
 import "C"
 import (
-	"errors"
 	"unsafe"
 )
 
@@ -350,6 +349,14 @@ func (ii InterfaceInfo) Method(index int) FunctionInfo {
 	return WrapFunctionInfo(unsafe.Pointer(ret))
 }
 
+// g_interface_info_find_vfunc
+func (ii InterfaceInfo) FindVFunc(name string) VFuncInfo {
+	gName := _GoStringToGString(name)
+	ret := C.g_interface_info_find_vfunc(ii.p(), gName)
+	C.free_gstring(gName)
+	return WrapVFuncInfo(unsafe.Pointer(ret))
+}
+
 type ObjectInfo struct {
 	RegisteredTypeInfo
 }
@@ -382,6 +389,37 @@ func (oi ObjectInfo) Method(index int) FunctionInfo {
 	return WrapFunctionInfo(unsafe.Pointer(ret))
 }
 
+// g_object_info_find_vfunc
+func (oi ObjectInfo) FindVFunc(name string) VFuncInfo {
+	gName := _GoStringToGString(name)
+	ret := C.g_object_info_find_vfunc(oi.p(), gName)
+	C.free_gstring(gName)
+	return WrapVFuncInfo(unsafe.Pointer(ret))
+}
+
+type VFuncInfo struct {
+	CallableInfo
+}
+
+func (vfi VFuncInfo) p() *C.GIVFuncInfo {
+	return (*C.GIVFuncInfo)(vfi.P)
+}
+
+func WrapVFuncInfo(p unsafe.Pointer) (ret VFuncInfo) {
+	ret.P = p
+	return
+}
+
+// g_vfunc_info_get_offset
+// 返回虚函数在类结构体中的偏移，不知道偏移时返回 -1。
+func (vfi VFuncInfo) Offset() int {
+	ret := int(C.g_vfunc_info_get_offset(vfi.p()))
+	if ret == 0xFFFF {
+		return -1
+	}
+	return ret
+}
+
 type StructInfo struct {
 	RegisteredTypeInfo
 }
@@ -521,6 +559,10 @@ func WrapFunctionInfo(p unsafe.Pointer) (ret FunctionInfo) {
 	return
 }
 
+func (fi FunctionInfo) Symbol() string {
+	return _GStringToGoString(C.g_function_info_get_symbol(fi.p()))
+}
+
 func (fi FunctionInfo) PrepInvoker() (Invoker, error) {
 	var err *C.GError
 	var cInvoker C.GIFunctionInvoker
@@ -569,11 +611,10 @@ func (invoker Invoker) Call(args []Argument, retVal *Argument, pOutArgs *Argumen
 
 // GError to os.Error, frees "err"
 func _GErrorToOSError(err *C.GError) (goerr error) {
-	goerr = errors.New(_GStringToGoString(err.message))
-	C.g_error_free(err)
-	return
+	return newGError(err)
 }
 
+// ToError 把 C 中的 GError 转换为 *GError，并释放它，ptr 为 nil 时返回 nil。
 func ToError(ptr unsafe.Pointer) (err error) {
 	if ptr == nil {
 		return nil
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EnumValue 是枚举或标志类型的一个值，用于实现生成的 String 方法和 Parse 函数。
+type EnumValue struct {
+	Value int64
+	// C 中的名字，比如 GTK_ORIENTATION_VERTICAL
+	Name string
+	// 简短的名字，比如 vertical
+	Nick string
+}
+
+// EnumString 返回枚举值 v 在 C 中的名字，如果没有找到，则返回类似 OrientationEnum(3) 这样的字符串。
+func EnumString(values []EnumValue, v int64, typeName string) string {
+	for _, value := range values {
+		if value.Value == v {
+			return value.Name
+		}
+	}
+	return typeName + "(" + strconv.FormatInt(v, 10) + ")"
+}
+
+// ParseEnum 根据 nick 或 C 中的名字查找枚举值。
+func ParseEnum(values []EnumValue, str string, typeName string) (int64, error) {
+	for _, value := range values {
+		if str == value.Nick || str == value.Name {
+			return value.Value, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %s value %q", typeName, str)
+}
+
+// FlagsString 返回标志 v 中设置了的各个标志在 C 中的名字，以 | 分隔，
+// 没有名字的剩余位以十六进制数的形式列在最后，如果 v 为 0 并且没有值为 0 的标志，则返回 "0"。
+func FlagsString(values []EnumValue, v int64) string {
+	if v == 0 {
+		for _, value := range values {
+			if value.Value == 0 {
+				return value.Name
+			}
+		}
+		return "0"
+	}
+
+	var names []string
+	remain := v
+	for _, value := range values {
+		if value.Value == 0 {
+			continue
+		}
+		// 多个位组合成的标志只有在它的位都被设置了，并且还有位没有被列出时才列出。
+		if v&value.Value == value.Value && remain&value.Value != 0 {
+			names = append(names, value.Name)
+			remain &^= value.Value
+		}
+	}
+	if remain != 0 {
+		names = append(names, "0x"+strconv.FormatInt(remain, 16))
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseFlags 解析以 | 分隔的多个标志，每个标志可以是 nick 或者 C 中的名字，首尾的空白字符会被忽略。
+func ParseFlags(values []EnumValue, str string, typeName string) (int64, error) {
+	var result int64
+	if strings.TrimSpace(str) == "" {
+		return 0, nil
+	}
+	for _, part := range strings.Split(str, "|") {
+		part = strings.TrimSpace(part)
+		v, err := ParseEnum(values, part, typeName)
+		if err != nil {
+			return 0, err
+		}
+		result |= v
+	}
+	return result, nil
+}
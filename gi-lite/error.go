@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+/*
+#include <glib.h>
+#include <stdlib.h>
+#cgo pkg-config: glib-2.0
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ErrNotSupported 由生成器不支持的函数的桩函数返回，这种函数的参数或返回值不能正确地转换，所以不会调用 C 函数。
+var ErrNotSupported = errors.New("gi: function not supported")
+
+// Quark 对应 GQuark，是一个字符串的唯一标识，比如错误域就是 quark。
+type Quark uint32
+
+// QuarkFromString 获取字符串 str 对应的 quark，如果还没有则创建。
+func QuarkFromString(str string) Quark {
+	cStr := C.CString(str)
+	q := C.g_quark_from_string(cStr)
+	C.free(unsafe.Pointer(cStr))
+	return Quark(q)
+}
+
+// String 返回 quark 对应的字符串。
+func (q Quark) String() string {
+	return C.GoString(C.g_quark_to_string(C.GQuark(q)))
+}
+
+// GError 对应 C 中的 GError，包含错误域、错误代码和错误消息。
+// 可以用 errors.Is(err, target) 判断错误是否是某个错误域中的某个错误代码，其中 target 是生成的错误代码枚举值，
+// 比如 gio 的 IOErrorNotFound，也可以用 errors.As 获取 *GError。
+type GError struct {
+	Domain  Quark
+	Code    int
+	Message string
+}
+
+func (e *GError) Error() string {
+	return e.Message
+}
+
+// Is 用于支持 errors.Is，当 target 是 ErrorCode 或 *GError，并且错误域和错误代码都相同时返回 true。
+func (e *GError) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorCode:
+		return e.Domain == t.ErrorDomain() && e.Code == t.ErrorCode()
+	case *GError:
+		return e.Domain == t.Domain && e.Code == t.Code
+	}
+	return false
+}
+
+// ErrorCode 由生成的错误代码枚举类型实现，它们的枚举值表示某个错误域中的一个错误代码。
+type ErrorCode interface {
+	error
+	ErrorDomain() Quark
+	ErrorCode() int
+}
+
+// ErrorCodeString 返回错误代码的描述，用于实现生成的错误代码枚举类型的 Error 方法。
+// 已知的错误代码返回它在 C 中的名字，和 String 方法一致，未知的错误代码返回错误域和错误代码的值。
+func ErrorCodeString(values []EnumValue, domain string, code int) string {
+	for _, value := range values {
+		if value.Value == int64(code) {
+			return value.Name
+		}
+	}
+	return fmt.Sprintf("%s: code %d", domain, code)
+}
+
+// newGError 把 C 中的 GError 转换为 *GError，并释放 err。
+func newGError(err *C.GError) *GError {
+	gErr := &GError{
+		Domain:  Quark(err.domain),
+		Code:    int(err.code),
+		Message: C.GoString(err.message),
+	}
+	C.g_error_free(err)
+	return gErr
+}
@@ -97,3 +97,138 @@ func TestStoreStruct(t *testing.T) {
 	assert.Equal(t, Uint2Ptr(1), s1.A.P)
 	assert.Equal(t, Uint2Ptr(2), s1.B.P)
 }
+
+func TestFlagsString(t *testing.T) {
+	values := []EnumValue{
+		{Value: 0, Name: "G_TEST_NONE", Nick: "none"},
+		{Value: 1, Name: "G_TEST_A", Nick: "a"},
+		{Value: 2, Name: "G_TEST_B", Nick: "b"},
+		{Value: 3, Name: "G_TEST_AB", Nick: "ab"},
+	}
+	assert.Equal(t, "G_TEST_NONE", FlagsString(values, 0))
+	assert.Equal(t, "G_TEST_A", FlagsString(values, 1))
+	assert.Equal(t, "G_TEST_A|G_TEST_B", FlagsString(values, 3))
+	assert.Equal(t, "G_TEST_B|0x8", FlagsString(values, 10))
+	assert.Equal(t, "0", FlagsString(values[1:], 0))
+
+	v, err := ParseFlags(values, "a | G_TEST_B", "TestFlags")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, v)
+
+	_, err = ParseFlags(values, "a|c", "TestFlags")
+	assert.NotNil(t, err)
+}
+
+func TestEnumString(t *testing.T) {
+	values := []EnumValue{
+		{Value: 0, Name: "GTK_ORIENTATION_HORIZONTAL", Nick: "horizontal"},
+		{Value: 1, Name: "GTK_ORIENTATION_VERTICAL", Nick: "vertical"},
+	}
+	assert.Equal(t, "GTK_ORIENTATION_VERTICAL", EnumString(values, 1, "OrientationEnum"))
+	assert.Equal(t, "OrientationEnum(3)", EnumString(values, 3, "OrientationEnum"))
+
+	v, err := ParseEnum(values, "vertical", "OrientationEnum")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, v)
+}
+
+func TestVFuncSlot(t *testing.T) {
+	// 模拟一个类结构体，offset 和 g_vfunc_info_get_offset 返回的一样，是从结构体开头算起的字节数。
+	type fakeClass struct {
+		gType  uintptr
+		flags  uint32
+		vfunc1 unsafe.Pointer
+		vfunc2 unsafe.Pointer
+	}
+	var class fakeClass
+	fn := Uint2Ptr(0x1234)
+	*vfuncSlot(unsafe.Pointer(&class), int(unsafe.Offsetof(class.vfunc2))) = fn
+	assert.Equal(t, fn, class.vfunc2)
+	assert.Nil(t, class.vfunc1)
+
+	class.vfunc1 = Uint2Ptr(0x5678)
+	assert.Equal(t, class.vfunc1, *vfuncSlot(unsafe.Pointer(&class), int(unsafe.Offsetof(class.vfunc1))))
+}
+
+func TestErrorCodeString(t *testing.T) {
+	values := []EnumValue{
+		{Value: 0, Name: "G_IO_ERROR_FAILED", Nick: "failed"},
+		{Value: 1, Name: "G_IO_ERROR_NOT_FOUND", Nick: "not-found"},
+	}
+	assert.Equal(t, "G_IO_ERROR_NOT_FOUND", ErrorCodeString(values, "g-io-error-quark", 1))
+	assert.Equal(t, "g-io-error-quark: code 100", ErrorCodeString(values, "g-io-error-quark", 100))
+}
+
+type testVFuncImpl interface {
+	VFuncActivate(parent func())
+}
+
+type testGoClassA struct {
+	calls *[]string
+}
+
+func (a *testGoClassA) VFuncActivate(parent func()) {
+	*a.calls = append(*a.calls, "A")
+	parent()
+}
+
+type testGoClassB struct {
+	calls *[]string
+	a     *testGoClassA
+}
+
+func (b *testGoClassB) VFuncActivate(parent func()) {
+	*b.calls = append(*b.calls, "B")
+	parent()
+}
+
+func TestSubclassChainUp(t *testing.T) {
+	// 两层在 Go 中定义的类型：B 派生自 A，A 派生自 C 中定义的类型，A 和 B 都覆盖了虚函数 activate。
+	// 类结构体的开头是 GType，可以用来模拟类结构体。
+	typeA := GType(0x7fff0001)
+	typeB := GType(0x7fff0002)
+	var calls []string
+	_subclassMapMu.Lock()
+	_subclassTypeMap[typeA] = &SubclassInfo{NewInstance: func(obj unsafe.Pointer) interface{} {
+		return &testGoClassA{calls: &calls}
+	}}
+	_subclassTypeMap[typeB] = &SubclassInfo{NewInstance: func(obj unsafe.Pointer) interface{} {
+		a, _ := GetTypeInstanceData(obj, typeA).(*testGoClassA)
+		return &testGoClassB{calls: &calls, a: a}
+	}}
+	_subclassMapMu.Unlock()
+	defer func() {
+		_subclassMapMu.Lock()
+		delete(_subclassTypeMap, typeA)
+		delete(_subclassTypeMap, typeB)
+		_subclassMapMu.Unlock()
+	}()
+
+	obj := unsafe.Pointer(new(uintptr))
+	bindInstance(obj, []GType{typeA, typeB})
+	bindInstance(obj, []GType{typeA, typeB}) // 重复绑定什么都不做
+	defer unbindInstance(obj)
+
+	b, ok := GetInstanceData(obj).(*testGoClassB)
+	assert.True(t, ok)
+	a, ok := GetTypeInstanceData(obj, typeA).(*testGoClassA)
+	assert.True(t, ok)
+	assert.Equal(t, a, b.a)
+
+	// 和生成的覆盖虚函数的 closure 一样，用填充虚函数的类结构体找到要调用的 Go 值。
+	classA := typeA
+	classB := typeB
+	cImpl := func() { calls = append(calls, "C") }
+	closureA := func() {
+		GetClassInstanceData(unsafe.Pointer(&classA), obj).(testVFuncImpl).VFuncActivate(cImpl)
+	}
+	closureB := func() {
+		GetClassInstanceData(unsafe.Pointer(&classB), obj).(testVFuncImpl).VFuncActivate(closureA)
+	}
+	closureB()
+	assert.Equal(t, []string{"B", "A", "C"}, calls)
+
+	unbindInstance(obj)
+	assert.Nil(t, GetInstanceData(obj))
+	assert.Nil(t, GetTypeInstanceData(obj, typeA))
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+/*
+#include <glib.h>
+
+static GHashTable *new_hash_table(int str_hash, int key_free, int value_free) {
+    return g_hash_table_new_full(str_hash ? g_str_hash : g_direct_hash,
+        str_hash ? g_str_equal : g_direct_equal,
+        key_free ? g_free : NULL,
+        value_free ? g_free : NULL);
+}
+
+#cgo pkg-config: glib-2.0
+*/
+import "C"
+import "unsafe"
+
+// HashFunc 是 GHashTable 中键的哈希方式
+type HashFunc uint
+
+const (
+	HashFuncDirect HashFunc = iota // g_direct_hash 和 g_direct_equal
+	HashFuncStr                    // g_str_hash 和 g_str_equal
+)
+
+// HashDestroy 是 GHashTable 被销毁或者移除元素时，键或值的释放方式
+type HashDestroy uint
+
+const (
+	HashDestroyNone HashDestroy = iota // 不释放
+	HashDestroyFree                    // 用 g_free 释放
+)
+
+// NewHashTable 创建一个 GHashTable，返回它的指针。
+func NewHashTable(hashFunc HashFunc, keyDestroy, valueDestroy HashDestroy) unsafe.Pointer {
+	ret := C.new_hash_table(C.int(hashFunc), C.int(keyDestroy), C.int(valueDestroy))
+	return unsafe.Pointer(ret)
+}
+
+func HashTableInsert(table, key, value unsafe.Pointer) {
+	C.g_hash_table_insert((*C.GHashTable)(table), C.gpointer(key), C.gpointer(value))
+}
+
+func HashTableUnref(table unsafe.Pointer) {
+	if table == nil {
+		return
+	}
+	C.g_hash_table_unref((*C.GHashTable)(table))
+}
+
+// HashTableForEach 遍历 GHashTable 中所有的键值对，table 可以为 nil。
+func HashTableForEach(table unsafe.Pointer, fn func(key, value unsafe.Pointer)) {
+	if table == nil {
+		return
+	}
+	var iter C.GHashTableIter
+	var key, value C.gpointer
+	C.g_hash_table_iter_init(&iter, (*C.GHashTable)(table))
+	for C.g_hash_table_iter_next(&iter, &key, &value) != 0 {
+		fn(unsafe.Pointer(key), unsafe.Pointer(value))
+	}
+}
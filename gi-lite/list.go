@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+/*
+#include <glib.h>
+
+static void list_free_full(GList *list) {
+    g_list_free_full(list, g_free);
+}
+
+static void slist_free_full(GSList *list) {
+    g_slist_free_full(list, g_free);
+}
+
+#cgo pkg-config: glib-2.0
+*/
+import "C"
+import "unsafe"
+
+// NewList 用 items 创建一个 GList，返回它的指针，items 为空时返回 nil。
+func NewList(items []unsafe.Pointer) unsafe.Pointer {
+	var list *C.GList
+	for i := len(items) - 1; i >= 0; i-- {
+		list = C.g_list_prepend(list, C.gpointer(items[i]))
+	}
+	return unsafe.Pointer(list)
+}
+
+// ListForEach 遍历 GList 中的所有元素，list 可以为 nil。
+func ListForEach(list unsafe.Pointer, fn func(item unsafe.Pointer)) {
+	for l := (*C.GList)(list); l != nil; l = l.next {
+		fn(unsafe.Pointer(l.data))
+	}
+}
+
+// ListFree 只释放 GList 本身，不释放元素。
+func ListFree(list unsafe.Pointer) {
+	C.g_list_free((*C.GList)(list))
+}
+
+// ListFreeFull 释放 GList，并且用 g_free 释放所有元素。
+func ListFreeFull(list unsafe.Pointer) {
+	C.list_free_full((*C.GList)(list))
+}
+
+// NewSList 用 items 创建一个 GSList，返回它的指针，items 为空时返回 nil。
+func NewSList(items []unsafe.Pointer) unsafe.Pointer {
+	var list *C.GSList
+	for i := len(items) - 1; i >= 0; i-- {
+		list = C.g_slist_prepend(list, C.gpointer(items[i]))
+	}
+	return unsafe.Pointer(list)
+}
+
+// SListForEach 遍历 GSList 中的所有元素，list 可以为 nil。
+func SListForEach(list unsafe.Pointer, fn func(item unsafe.Pointer)) {
+	for l := (*C.GSList)(list); l != nil; l = l.next {
+		fn(unsafe.Pointer(l.data))
+	}
+}
+
+// SListFree 只释放 GSList 本身，不释放元素。
+func SListFree(list unsafe.Pointer) {
+	C.g_slist_free((*C.GSList)(list))
+}
+
+// SListFreeFull 释放 GSList，并且用 g_free 释放所有元素。
+func SListFreeFull(list unsafe.Pointer) {
+	C.slist_free_full((*C.GSList)(list))
+}
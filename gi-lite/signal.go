@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+/*
+#include <glib-object.h>
+
+extern void giSignalMarshal(guint id, GValue *ret, guint n_params, GValue *params);
+extern void giSignalClosureFinalize(gpointer data, GClosure *closure);
+
+static void signal_marshal(GClosure *closure, GValue *ret, guint n_params,
+    const GValue *params, gpointer invocation_hint, gpointer marshal_data) {
+    giSignalMarshal(GPOINTER_TO_UINT(closure->data), ret, n_params, (GValue*)(params));
+}
+
+static gulong signal_connect(gpointer obj, const gchar *detailed_signal, gboolean after, guint id) {
+    GClosure *closure = g_closure_new_simple(sizeof(GClosure), GUINT_TO_POINTER(id));
+    g_closure_set_marshal(closure, signal_marshal);
+    g_closure_add_finalize_notifier(closure, GUINT_TO_POINTER(id), giSignalClosureFinalize);
+    return g_signal_connect_closure(obj, detailed_signal, closure, after);
+}
+
+#cgo pkg-config: gobject-2.0
+*/
+import "C"
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"unsafe"
+)
+
+// SignalMarshalFunc 是生成的信号处理代码，params 是信号的参数，第一个是发出信号的对象，
+// ret 用于存放信号的返回值，信号没有返回值时 ret.P 为 nil。
+type SignalMarshalFunc func(params []Value, ret Value)
+
+var _signalNextId uint = 1
+var _signalMap = make(map[uint]SignalMarshalFunc)
+var _signalMapMu sync.RWMutex
+
+// SignalConnect 把 fn 连接到对象 obj 的 detailedSignal 信号，返回信号处理器的 id。
+// 当对象被销毁或者信号处理器被断开时，fn 会被自动移除。
+func SignalConnect(obj unsafe.Pointer, detailedSignal string, after bool, fn SignalMarshalFunc) uint {
+	_signalMapMu.Lock()
+	id := _signalNextId
+	_signalNextId++
+	_signalMap[id] = fn
+	_signalMapMu.Unlock()
+
+	cSignal := (*C.gchar)(CString(detailedSignal))
+	ret := C.signal_connect(C.gpointer(obj), cSignal, C.gboolean(Bool2Int(after)), C.guint(id))
+	Free(unsafe.Pointer(cSignal))
+	return uint(ret)
+}
+
+//export giSignalClosureFinalize
+func giSignalClosureFinalize(data C.gpointer, closure *C.GClosure) {
+	id := uint(uintptr(data))
+	_signalMapMu.Lock()
+	delete(_signalMap, id)
+	_signalMapMu.Unlock()
+}
+
+//export giSignalMarshal
+func giSignalMarshal(id C.guint, ret *C.GValue, nParams C.guint, params *C.GValue) {
+	_signalMapMu.RLock()
+	fn := _signalMap[uint(id)]
+	_signalMapMu.RUnlock()
+	if fn == nil {
+		return
+	}
+
+	defer func() {
+		err := recover()
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "signal handler panic with error:", err)
+			debug.PrintStack()
+		}
+	}()
+
+	n := int(nParams)
+	values := make([]Value, n)
+	for i := 0; i < n; i++ {
+		values[i].P = unsafe.Pointer(uintptr(unsafe.Pointer(params)) + uintptr(i)*C.sizeof_GValue)
+	}
+	fn(values, Value{P: unsafe.Pointer(ret)})
+}
@@ -0,0 +1,486 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+/*
+#include <glib-object.h>
+#include <girepository.h>
+#include <girffi.h>
+#include <string.h>
+
+extern void giSubclassClassInit(gpointer g_class, guint id);
+extern void giSubclassInstanceInit(GTypeInstance *instance, gpointer g_class);
+extern void giSubclassFinalize(GObject *obj);
+
+static void subclass_finalize(GObject *obj) {
+    giSubclassFinalize(obj);
+
+    // 跳过所有在 Go 中注册的祖先类型，调用最近的非 Go 类型的 finalize。
+    GType type = G_OBJECT_TYPE(obj);
+    GObjectClass *klass = g_type_class_peek(type);
+    while (klass->finalize == subclass_finalize) {
+        type = g_type_parent(type);
+        klass = g_type_class_peek(type);
+    }
+    klass->finalize(obj);
+}
+
+static void subclass_class_init(gpointer g_class, gpointer class_data) {
+    G_OBJECT_CLASS(g_class)->finalize = subclass_finalize;
+    giSubclassClassInit(g_class, GPOINTER_TO_UINT(class_data));
+}
+
+static GType register_subclass(GType parent, const gchar *name, guint id) {
+    if (!g_type_is_a(parent, G_TYPE_OBJECT)) {
+        return G_TYPE_INVALID;
+    }
+    GTypeQuery query;
+    g_type_query(parent, &query);
+    if (query.type == G_TYPE_INVALID) {
+        return G_TYPE_INVALID;
+    }
+    GTypeInfo info = {0};
+    info.class_size = query.class_size;
+    info.class_init = subclass_class_init;
+    info.class_data = GUINT_TO_POINTER(id);
+    info.instance_size = query.instance_size;
+    info.instance_init = (GInstanceInitFunc)(giSubclassInstanceInit);
+    return g_type_register_static(parent, name, &info, 0);
+}
+
+extern void giSubclassInterfaceInit(gpointer g_iface, guint id);
+
+static void subclass_interface_init(gpointer g_iface, gpointer iface_data) {
+    giSubclassInterfaceInit(g_iface, GPOINTER_TO_UINT(iface_data));
+}
+
+static gboolean add_interface(GType type, GType iface_type, guint id) {
+    if (!G_TYPE_IS_INTERFACE(iface_type)) {
+        return FALSE;
+    }
+    GInterfaceInfo info = {0};
+    info.interface_init = subclass_interface_init;
+    info.interface_data = GUINT_TO_POINTER(id);
+    g_type_add_interface_static(type, iface_type, &info);
+    // 不满足接口的前提条件等情况下 g_type_add_interface_static 只打印警告，不会添加接口。
+    return g_type_is_a(type, iface_type);
+}
+
+static GType class_type(gpointer g_class) {
+    return G_TYPE_FROM_CLASS(g_class);
+}
+
+static GType iface_instance_type(gpointer g_iface) {
+    return ((GTypeInterface*)g_iface)->g_instance_type;
+}
+
+static gpointer parent_struct(gpointer g_struct, gboolean is_iface) {
+    if (is_iface) {
+        return g_type_interface_peek_parent(g_struct);
+    }
+    return g_type_class_peek_parent(g_struct);
+}
+
+// 用 args 调用地址为 addr 的虚函数，返回值写入 result。
+static gboolean call_vfunc(GICallableInfo *info, gpointer addr, void *result, void **args, GError **error) {
+    GIFunctionInvoker invoker;
+    if (!g_function_invoker_new_for_address(addr, info, &invoker, error)) {
+        return FALSE;
+    }
+    ffi_call(&invoker.cif, FFI_FN(addr), result, args);
+    g_function_invoker_destroy(&invoker);
+    return TRUE;
+}
+
+// 把虚函数的返回值置零，ffi 的返回值至少占用 ffi_arg 的大小。
+static void zero_vfunc_result(GICallableInfo *info, void *result) {
+    if (result == NULL) {
+        return;
+    }
+    GITypeInfo *ret_type = g_callable_info_get_return_type(info);
+    ffi_type *ffi_ret_type = g_type_info_get_ffi_type(ret_type);
+    g_base_info_unref(ret_type);
+    if (ffi_ret_type == &ffi_type_void) {
+        return;
+    }
+    size_t size = ffi_ret_type->size;
+    if (size < sizeof(ffi_arg)) {
+        size = sizeof(ffi_arg);
+    }
+    memset(result, 0, size);
+}
+
+#cgo pkg-config: gobject-2.0 gobject-introspection-1.0
+*/
+import "C"
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// SubclassInfo 描述一个在 Go 中定义的 GObject 子类型。
+type SubclassInfo struct {
+	// 新类型的名字，在进程内必须唯一
+	Name string
+	// 父类型，必须是 GObject 或者它的子类型
+	Parent GType
+	// ClassInit 在类初始化时被调用，class 是类结构体的指针，
+	// 一般在这里调用生成的 OverrideXxxVFuncs 函数覆盖虚函数。
+	ClassInit func(class unsafe.Pointer)
+	// NewInstance 在实例初始化时被调用，返回和实例 obj 绑定的 Go 值，在这个类型的 ClassInit 中覆盖的
+	// 虚函数的 VFuncXxx 方法在这个值上调用。实例被销毁时解除绑定。
+	// 如果父类型也是在 Go 中定义的，父类型的 NewInstance 先被调用，可以用 GetTypeInstanceData 获取父类型的值。
+	NewInstance func(obj unsafe.Pointer) interface{}
+	// 新类型要实现的 GInterface 列表
+	Interfaces []SubclassInterface
+}
+
+// SubclassInterface 描述在 Go 中定义的类型要实现的一个 GInterface。
+type SubclassInterface struct {
+	// 接口的类型
+	Type GType
+	// Init 在接口初始化时被调用，iface 是接口结构体的指针，
+	// 一般在这里调用生成的 ImplementXxx 函数填充接口的虚函数。
+	Init func(iface unsafe.Pointer)
+}
+
+var _subclassNextId uint = 1
+var _subclassMap = make(map[uint]*SubclassInfo)
+var _subclassTypeMap = make(map[GType]*SubclassInfo)
+var _subclassMapMu sync.RWMutex
+
+var _ifaceInitNextId uint = 1
+var _ifaceInitMap = make(map[uint]func(iface unsafe.Pointer))
+var _ifaceInitMapMu sync.RWMutex
+
+// instanceData 是和一个实例绑定的 Go 值
+type instanceData struct {
+	values map[GType]interface{} // 键是实例的类型及其祖先类型中在 Go 中定义的类型
+	last   interface{}           // 实例的类型中最后代的在 Go 中定义的类型的值
+}
+
+var _instanceMap = make(map[unsafe.Pointer]*instanceData)
+var _instanceMapMu sync.RWMutex
+
+// RegisterSubclass 注册一个从 info.Parent 派生的新 GType。
+// 无法添加 info.Interfaces 中的某个接口时返回错误，此时已经注册的 GType 不能再使用。
+func RegisterSubclass(info SubclassInfo) (GType, error) {
+	_subclassMapMu.Lock()
+	id := _subclassNextId
+	_subclassNextId++
+	_subclassMap[id] = &info
+	_subclassMapMu.Unlock()
+
+	cName := (*C.gchar)(CString(info.Name))
+	ret := C.register_subclass(C.GType(info.Parent), cName, C.guint(id))
+	Free(unsafe.Pointer(cName))
+	if ret == 0 {
+		_subclassMapMu.Lock()
+		delete(_subclassMap, id)
+		_subclassMapMu.Unlock()
+		return 0, fmt.Errorf("gi: failed to register type %q derived from %v", info.Name, typeName(info.Parent))
+	}
+
+	for _, iface := range info.Interfaces {
+		_ifaceInitMapMu.Lock()
+		ifaceId := _ifaceInitNextId
+		_ifaceInitNextId++
+		_ifaceInitMap[ifaceId] = iface.Init
+		_ifaceInitMapMu.Unlock()
+
+		if C.add_interface(ret, C.GType(iface.Type), C.guint(ifaceId)) == 0 {
+			_ifaceInitMapMu.Lock()
+			delete(_ifaceInitMap, ifaceId)
+			_ifaceInitMapMu.Unlock()
+			return 0, fmt.Errorf("gi: failed to add interface %v to type %q", typeName(iface.Type), info.Name)
+		}
+	}
+	return GType(ret), nil
+}
+
+// typeName 返回类型 t 的名字，用于错误信息。
+func typeName(t GType) string {
+	name := C.g_type_name(C.GType(t))
+	if name == nil {
+		return fmt.Sprintf("GType(%d)", t)
+	}
+	return C.GoString((*C.char)(unsafe.Pointer(name)))
+}
+
+// GetInstanceData 返回和实例 obj 绑定的 Go 值，即 obj 的类型中最后代的在 Go 中定义的类型的 NewInstance 返回的值，
+// 如果 obj 不是 Go 中定义的类型的实例，返回 nil。
+func GetInstanceData(obj unsafe.Pointer) interface{} {
+	_instanceMapMu.RLock()
+	defer _instanceMapMu.RUnlock()
+	if data := _instanceMap[obj]; data != nil {
+		return data.last
+	}
+	return nil
+}
+
+// GetTypeInstanceData 返回在 Go 中定义的类型 t 的 NewInstance 为实例 obj 返回的 Go 值，t 是 obj 的类型或者它的祖先类型，
+// 找不到时返回 nil。
+func GetTypeInstanceData(obj unsafe.Pointer, t GType) interface{} {
+	_instanceMapMu.RLock()
+	defer _instanceMapMu.RUnlock()
+	if data := _instanceMap[obj]; data != nil {
+		return data.values[t]
+	}
+	return nil
+}
+
+// GetClassInstanceData 返回类结构体 class 所属的类型为实例 obj 创建的 Go 值，用于在覆盖虚函数的 closure 中
+// 调用覆盖此虚函数的类型的 VFuncXxx 方法，而不是实例的最后代类型的。
+func GetClassInstanceData(class, obj unsafe.Pointer) interface{} {
+	return GetTypeInstanceData(obj, GType(C.class_type(C.gpointer(class))))
+}
+
+// GetInterfaceInstanceData 返回实现了接口结构体 iface 的类型为实例 obj 创建的 Go 值，
+// 用于在填充接口虚函数的 closure 中调用实现此接口的类型的 VFuncXxx 方法。
+func GetInterfaceInstanceData(iface, obj unsafe.Pointer) interface{} {
+	return GetTypeInstanceData(obj, GType(C.iface_instance_type(C.gpointer(iface))))
+}
+
+// bindInstance 为实例 obj 依次调用 types 中各个类型的 NewInstance，把返回的 Go 值和 obj 绑定，
+// types 是 obj 的类型及其祖先类型中在 Go 中定义的类型，从祖先到后代排列。obj 已经绑定时什么都不做。
+func bindInstance(obj unsafe.Pointer, types []GType) {
+	data := &instanceData{values: make(map[GType]interface{}, len(types))}
+	_instanceMapMu.Lock()
+	if _, ok := _instanceMap[obj]; ok {
+		_instanceMapMu.Unlock()
+		return
+	}
+	_instanceMap[obj] = data
+	_instanceMapMu.Unlock()
+
+	for _, t := range types {
+		_subclassMapMu.RLock()
+		info := _subclassTypeMap[t]
+		_subclassMapMu.RUnlock()
+		if info == nil || info.NewInstance == nil {
+			continue
+		}
+		// 调用 NewInstance 时不持有锁，它可以用 GetTypeInstanceData 获取祖先类型的值。
+		v := info.NewInstance(obj)
+		_instanceMapMu.Lock()
+		data.values[t] = v
+		data.last = v
+		_instanceMapMu.Unlock()
+	}
+}
+
+// unbindInstance 解除实例 obj 和 Go 值的绑定
+func unbindInstance(obj unsafe.Pointer) {
+	_instanceMapMu.Lock()
+	delete(_instanceMap, obj)
+	_instanceMapMu.Unlock()
+}
+
+// 以 "命名空间.类型名.虚函数名" 为 key 缓存 VFuncInfo，缓存的 VFuncInfo 不会被释放。
+var _vfuncInfoCache = make(map[string]VFuncInfo)
+var _vfuncInfoCacheMu sync.Mutex
+
+// findVFunc 查找命名空间 namespace 中的类型 typeName 的虚函数 vfuncName，infoType 是
+// INFO_TYPE_OBJECT 或者 INFO_TYPE_INTERFACE，找不到时返回的 VFuncInfo 的 P 为 nil。
+func findVFunc(namespace, typeName, vfuncName string, infoType InfoType) VFuncInfo {
+	key := namespace + "." + typeName + "." + vfuncName
+	_vfuncInfoCacheMu.Lock()
+	defer _vfuncInfoCacheMu.Unlock()
+	if vfi, ok := _vfuncInfoCache[key]; ok {
+		return vfi
+	}
+
+	var vfi VFuncInfo
+	bi := defaultRepo.FindByName(namespace, typeName)
+	if bi.P == nil {
+		return vfi
+	}
+	defer bi.Unref()
+	if bi.Type() != infoType {
+		return vfi
+	}
+	if infoType == INFO_TYPE_OBJECT {
+		vfi = WrapObjectInfo(bi.P).FindVFunc(vfuncName)
+	} else {
+		vfi = WrapInterfaceInfo(bi.P).FindVFunc(vfuncName)
+	}
+	if vfi.P != nil {
+		_vfuncInfoCache[key] = vfi
+	}
+	return vfi
+}
+
+// OverrideVFunc 用 fn 覆盖类结构体 class 中的虚函数，虚函数由命名空间 namespace 中的对象类型 typeName
+// 的虚函数 vfuncName 确定。fn 的参数 args 中的第一个元素是实例指针的指针。
+func OverrideVFunc(class unsafe.Pointer, namespace, typeName, vfuncName string, fn FClosureFunc) bool {
+	vfi := findVFunc(namespace, typeName, vfuncName, INFO_TYPE_OBJECT)
+	return setVFunc(class, vfi, fn)
+}
+
+// OverrideInterfaceVFunc 用 fn 填充接口结构体 iface 中的虚函数，虚函数由命名空间 namespace 中的接口类型
+// ifaceName 的虚函数 vfuncName 确定。fn 的参数 args 中的第一个元素是实例指针的指针。
+func OverrideInterfaceVFunc(iface unsafe.Pointer, namespace, ifaceName, vfuncName string, fn FClosureFunc) bool {
+	vfi := findVFunc(namespace, ifaceName, vfuncName, INFO_TYPE_INTERFACE)
+	return setVFunc(iface, vfi, fn)
+}
+
+// vfuncSlot 返回类结构体或者接口结构体 structPtr 中偏移为 offset 的虚函数指针的地址。
+func vfuncSlot(structPtr unsafe.Pointer, offset int) *unsafe.Pointer {
+	return (*unsafe.Pointer)(unsafe.Pointer(uintptr(structPtr) + uintptr(offset)))
+}
+
+// setVFunc 把类结构体或者接口结构体 structPtr 中虚函数 vfi 的函数指针设置为 fn 的 closure。
+func setVFunc(structPtr unsafe.Pointer, vfi VFuncInfo, fn FClosureFunc) bool {
+	if vfi.P == nil {
+		return false
+	}
+	offset := vfi.Offset()
+	if offset < 0 {
+		return false
+	}
+	// 类和接口一旦初始化就不会被销毁，所以这个 closure 永远不会被注销。
+	_, fnPtr := RegisterFClosure(fn, ScopeNotified, vfi.CallableInfo)
+	*vfuncSlot(structPtr, offset) = fnPtr
+	return true
+}
+
+// VFuncParent 表示被覆盖的虚函数在父类型中的实现，覆盖虚函数的 VFuncXxx 方法可以用它调用父类型的实现。
+type VFuncParent struct {
+	parent unsafe.Pointer // 父类型的类结构体或者接口结构体
+	vfi    VFuncInfo
+	result unsafe.Pointer
+	args   []unsafe.Pointer
+}
+
+// NewVFuncParent 返回类结构体 class 中被覆盖的虚函数在父类型中的实现，虚函数由命名空间 namespace 中的
+// 对象类型 typeName 的虚函数 vfuncName 确定，result 和 args 是虚函数被调用时 closure 的参数。
+func NewVFuncParent(class unsafe.Pointer, namespace, typeName, vfuncName string, result unsafe.Pointer,
+	args []unsafe.Pointer) VFuncParent {
+	return VFuncParent{
+		parent: unsafe.Pointer(C.parent_struct(C.gpointer(class), 0)),
+		vfi:    findVFunc(namespace, typeName, vfuncName, INFO_TYPE_OBJECT),
+		result: result,
+		args:   args,
+	}
+}
+
+// NewInterfaceVFuncParent 返回接口结构体 iface 中被填充的虚函数在父类型中的实现，虚函数由命名空间 namespace 中的
+// 接口类型 ifaceName 的虚函数 vfuncName 确定，result 和 args 是虚函数被调用时 closure 的参数。
+func NewInterfaceVFuncParent(iface unsafe.Pointer, namespace, ifaceName, vfuncName string, result unsafe.Pointer,
+	args []unsafe.Pointer) VFuncParent {
+	return VFuncParent{
+		parent: unsafe.Pointer(C.parent_struct(C.gpointer(iface), 1)),
+		vfi:    findVFunc(namespace, ifaceName, vfuncName, INFO_TYPE_INTERFACE),
+		result: result,
+		args:   args,
+	}
+}
+
+func (p VFuncParent) addr() unsafe.Pointer {
+	if p.parent == nil || p.vfi.P == nil {
+		return nil
+	}
+	offset := p.vfi.Offset()
+	if offset < 0 {
+		return nil
+	}
+	return *vfuncSlot(p.parent, offset)
+}
+
+// Exists 返回父类型是否实现了这个虚函数。
+func (p VFuncParent) Exists() bool {
+	return p.addr() != nil
+}
+
+// Call 用虚函数被调用时的参数调用父类型的实现，父类型的实现的返回值写入虚函数的返回值，可以用 Result 读取。
+// 父类型没有实现这个虚函数或者调用失败时把虚函数的返回值置零，返回 false。
+func (p VFuncParent) Call() bool {
+	if p.vfi.P == nil {
+		return false
+	}
+	info := (*C.GICallableInfo)(p.vfi.P)
+	addr := p.addr()
+	if addr != nil {
+		var args *unsafe.Pointer
+		if len(p.args) > 0 {
+			args = &p.args[0]
+		}
+		var err *C.GError
+		if C.call_vfunc(info, C.gpointer(addr), p.result, args, &err) != 0 {
+			return true
+		}
+		C.g_error_free(err)
+	}
+	C.zero_vfunc_result(info, p.result)
+	return false
+}
+
+// Result 返回虚函数的返回值的指针，调用 Call 之后可以从这里读取父类型的实现的返回值。
+// 覆盖虚函数的 VFuncXxx 方法返回后，它的返回值会覆盖这里的值。
+func (p VFuncParent) Result() unsafe.Pointer {
+	return p.result
+}
+
+//export giSubclassClassInit
+func giSubclassClassInit(gClass C.gpointer, id C.guint) {
+	_subclassMapMu.Lock()
+	info := _subclassMap[uint(id)]
+	if info != nil {
+		_subclassTypeMap[GType(C.class_type(gClass))] = info
+	}
+	_subclassMapMu.Unlock()
+
+	if info != nil && info.ClassInit != nil {
+		info.ClassInit(unsafe.Pointer(gClass))
+	}
+}
+
+//export giSubclassInstanceInit
+func giSubclassInstanceInit(instance *C.GTypeInstance, gClass C.gpointer) {
+	// 如果有多个在 Go 中注册的祖先类型，这个函数会被调用多次，gClass 都是实例的类型的类结构体，
+	// 第一次调用时就为所有在 Go 中定义的类型创建 Go 值，之后的调用什么都不做。
+	var types []GType
+	_subclassMapMu.RLock()
+	for t := GType(C.class_type(gClass)); t != 0; t = GType(C.g_type_parent(C.GType(t))) {
+		if _, ok := _subclassTypeMap[t]; ok {
+			types = append([]GType{t}, types...)
+		}
+	}
+	_subclassMapMu.RUnlock()
+	bindInstance(unsafe.Pointer(instance), types)
+}
+
+//export giSubclassFinalize
+func giSubclassFinalize(obj *C.GObject) {
+	unbindInstance(unsafe.Pointer(obj))
+}
+
+//export giSubclassInterfaceInit
+func giSubclassInterfaceInit(gIface C.gpointer, id C.guint) {
+	_ifaceInitMapMu.RLock()
+	initFn := _ifaceInitMap[uint(id)]
+	_ifaceInitMapMu.RUnlock()
+	if initFn != nil {
+		initFn(unsafe.Pointer(gIface))
+	}
+}
@@ -183,30 +183,33 @@ func GetCallableInfo(namespace, name string) CallableInfo {
 	return WrapCallableInfo(bi.P)
 }
 
+// InvokerCache 缓存函数的 Invoker 和类型的 GType。
+// Invoker 以 C 函数的符号名为 key，GType 以 "命名空间.类型名" 为 key，
+// 所以各个命名空间可以独立生成代码，手写的代码也不需要关心编号。
 type InvokerCache struct {
 	namespace string
 	mu        sync.RWMutex
-	m         map[uint]Invoker
-	typeMap   map[uint]GType
+	m         map[string]Invoker
+	typeMap   map[string]GType
 }
 
 func NewInvokerCache(ns string) *InvokerCache {
 	return &InvokerCache{
 		namespace: ns,
-		m:         make(map[uint]Invoker),
-		typeMap:   make(map[uint]GType),
+		m:         make(map[string]Invoker),
+		typeMap:   make(map[string]GType),
 	}
 }
 
-func (ic *InvokerCache) put(id uint, invoker Invoker) {
+func (ic *InvokerCache) put(symbol string, invoker Invoker) {
 	ic.mu.Lock()
-	ic.m[id] = invoker
+	ic.m[symbol] = invoker
 	ic.mu.Unlock()
 }
 
-func (ic *InvokerCache) putGType(id uint, gType GType) {
+func (ic *InvokerCache) putGType(key string, gType GType) {
 	ic.mu.Lock()
-	ic.typeMap[id] = gType
+	ic.typeMap[key] = gType
 	ic.mu.Unlock()
 }
 
@@ -216,9 +219,10 @@ func DefaultRepository() Repository {
 	return defaultRepo
 }
 
-func (ic *InvokerCache) GetGType1(id uint, ns, typeName string) GType {
+func (ic *InvokerCache) GetGType1(ns, typeName string) GType {
+	key := ns + "." + typeName
 	ic.mu.RLock()
-	gType, ok := ic.typeMap[id]
+	gType, ok := ic.typeMap[key]
 	ic.mu.RUnlock()
 	if ok {
 		return gType
@@ -233,12 +237,12 @@ func (ic *InvokerCache) GetGType1(id uint, ns, typeName string) GType {
 
 	rti := WrapRegisteredTypeInfo(bi.P)
 	gType = rti.GetGType()
-	ic.putGType(id, gType)
+	ic.putGType(key, gType)
 	return gType
 }
 
-func (ic *InvokerCache) GetGType(id uint, typeName string) GType {
-	return ic.GetGType1(id, ic.namespace, typeName)
+func (ic *InvokerCache) GetGType(typeName string) GType {
+	return ic.GetGType1(ic.namespace, typeName)
 }
 
 // 需要 unref 返回值
@@ -294,9 +298,10 @@ type infoWithMethod interface {
 	NumMethods() int
 }
 
-func (ic *InvokerCache) Get1(id uint, ns, nameLv1, nameLv2 string, idxLv1, idxLv2 int, infoType InfoType, flags FindMethodFlags) (Invoker, error) {
+// Get1 获取 C 函数 symbol 的 Invoker，nameLv1, nameLv2, idxLv1, idxLv2 用于在 ns 命名空间中查找函数信息。
+func (ic *InvokerCache) Get1(ns, symbol, nameLv1, nameLv2 string, idxLv1, idxLv2 int, infoType InfoType, flags FindMethodFlags) (Invoker, error) {
 	ic.mu.RLock()
-	invoker, ok := ic.m[id]
+	invoker, ok := ic.m[symbol]
 	ic.mu.RUnlock()
 	if ok {
 		return invoker, nil
@@ -340,16 +345,22 @@ func (ic *InvokerCache) Get1(id uint, ns, nameLv1, nameLv2 string, idxLv1, idxLv
 		return Invoker{}, fmt.Errorf("unsupported info type %s", bi.Type())
 	}
 
+	// 防止生成代码或手写代码与 typelib 不一致
+	if funcSymbol := funcInfo.Symbol(); funcSymbol != symbol {
+		return Invoker{}, fmt.Errorf("symbol mismatch, want %v, but function %q has symbol %v in namespace %v",
+			symbol, funcInfo.Name(), funcSymbol, ns)
+	}
+
 	invoker, err := funcInfo.PrepInvoker()
 	if err != nil {
 		return Invoker{}, err
 	}
-	ic.put(id, invoker)
+	ic.put(symbol, invoker)
 	return invoker, nil
 }
 
-func (ic *InvokerCache) Get(id uint, nameLv1, nameLv2 string, idxLv1, idxLv2 int, infoType InfoType, flags FindMethodFlags) (Invoker, error) {
-	return ic.Get1(id, ic.namespace, nameLv1, nameLv2, idxLv1, idxLv2, infoType, flags)
+func (ic *InvokerCache) Get(symbol, nameLv1, nameLv2 string, idxLv1, idxLv2 int, infoType InfoType, flags FindMethodFlags) (Invoker, error) {
+	return ic.Get1(ic.namespace, symbol, nameLv1, nameLv2, idxLv1, idxLv2, infoType, flags)
 }
 
 func Int2Bool(v int) bool {
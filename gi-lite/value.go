@@ -0,0 +1,318 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+/*
+#include <glib-object.h>
+
+// 按属性的类型初始化一个 GValue，找不到属性时返回 NULL。
+static GValue *new_property_value(GObject *obj, const gchar *name) {
+    GParamSpec *pspec = g_object_class_find_property(G_OBJECT_GET_CLASS(obj), name);
+    if (pspec == NULL) {
+        return NULL;
+    }
+    GValue *value = g_new0(GValue, 1);
+    g_value_init(value, G_PARAM_SPEC_VALUE_TYPE(pspec));
+    return value;
+}
+
+static void free_value(GValue *value) {
+    g_value_unset(value);
+    g_free(value);
+}
+
+// 整数、enum 和 flags 都按 gint64 存取，无符号数的位模式保持不变。
+static gint64 value_get_int64(const GValue *value) {
+    switch (G_TYPE_FUNDAMENTAL(G_VALUE_TYPE(value))) {
+    case G_TYPE_CHAR:
+        return g_value_get_schar(value);
+    case G_TYPE_UCHAR:
+        return g_value_get_uchar(value);
+    case G_TYPE_INT:
+        return g_value_get_int(value);
+    case G_TYPE_UINT:
+        return g_value_get_uint(value);
+    case G_TYPE_LONG:
+        return g_value_get_long(value);
+    case G_TYPE_ULONG:
+        return g_value_get_ulong(value);
+    case G_TYPE_INT64:
+        return g_value_get_int64(value);
+    case G_TYPE_UINT64:
+        return g_value_get_uint64(value);
+    case G_TYPE_ENUM:
+        return g_value_get_enum(value);
+    case G_TYPE_FLAGS:
+        return g_value_get_flags(value);
+    }
+    return 0;
+}
+
+static void value_set_int64(GValue *value, gint64 v) {
+    switch (G_TYPE_FUNDAMENTAL(G_VALUE_TYPE(value))) {
+    case G_TYPE_CHAR:
+        g_value_set_schar(value, v);
+        break;
+    case G_TYPE_UCHAR:
+        g_value_set_uchar(value, v);
+        break;
+    case G_TYPE_INT:
+        g_value_set_int(value, v);
+        break;
+    case G_TYPE_UINT:
+        g_value_set_uint(value, v);
+        break;
+    case G_TYPE_LONG:
+        g_value_set_long(value, v);
+        break;
+    case G_TYPE_ULONG:
+        g_value_set_ulong(value, v);
+        break;
+    case G_TYPE_INT64:
+        g_value_set_int64(value, v);
+        break;
+    case G_TYPE_UINT64:
+        g_value_set_uint64(value, v);
+        break;
+    case G_TYPE_ENUM:
+        g_value_set_enum(value, v);
+        break;
+    case G_TYPE_FLAGS:
+        g_value_set_flags(value, v);
+        break;
+    }
+}
+
+static gdouble value_get_double(const GValue *value) {
+    if (G_VALUE_HOLDS_FLOAT(value)) {
+        return g_value_get_float(value);
+    }
+    return g_value_get_double(value);
+}
+
+static void value_set_double(GValue *value, gdouble v) {
+    if (G_VALUE_HOLDS_FLOAT(value)) {
+        g_value_set_float(value, v);
+    } else {
+        g_value_set_double(value, v);
+    }
+}
+
+// dup 不为 0 时，boxed 和 variant 类型的值返回的是副本，由调用者负责释放。
+static gpointer value_get_pointer(const GValue *value, int dup) {
+    if (G_VALUE_HOLDS_OBJECT(value)) {
+        return g_value_get_object(value);
+    }
+    switch (G_TYPE_FUNDAMENTAL(G_VALUE_TYPE(value))) {
+    case G_TYPE_BOXED:
+        return dup ? g_value_dup_boxed(value) : g_value_get_boxed(value);
+    case G_TYPE_POINTER:
+        return g_value_get_pointer(value);
+    case G_TYPE_PARAM:
+        return g_value_get_param(value);
+    case G_TYPE_VARIANT:
+        return dup ? g_value_dup_variant(value) : g_value_get_variant(value);
+    }
+    return NULL;
+}
+
+static void value_set_pointer(GValue *value, gpointer v) {
+    if (G_VALUE_HOLDS_OBJECT(value)) {
+        g_value_set_object(value, v);
+        return;
+    }
+    switch (G_TYPE_FUNDAMENTAL(G_VALUE_TYPE(value))) {
+    case G_TYPE_BOXED:
+        g_value_set_boxed(value, v);
+        break;
+    case G_TYPE_POINTER:
+        g_value_set_pointer(value, v);
+        break;
+    case G_TYPE_PARAM:
+        g_value_set_param(value, v);
+        break;
+    case G_TYPE_VARIANT:
+        g_value_set_variant(value, v);
+        break;
+    }
+}
+
+#cgo pkg-config: gobject-2.0
+*/
+import "C"
+import "unsafe"
+
+// Value 是 GValue 的简单封装，供生成的属性存取方法使用。
+// P 为 nil 时，读取方法都返回零值，设置方法什么也不做。
+type Value struct {
+	P unsafe.Pointer
+}
+
+func (v Value) native() *C.GValue {
+	return (*C.GValue)(v.P)
+}
+
+// NewPropertyValue 创建一个按对象 obj 的 name 属性的类型初始化的 Value，
+// 如果对象没有这个属性，返回的 Value 的 P 为 nil。
+func NewPropertyValue(obj unsafe.Pointer, name string) Value {
+	cName := (*C.gchar)(CString(name))
+	ret := C.new_property_value((*C.GObject)(obj), cName)
+	Free(unsafe.Pointer(cName))
+	return Value{P: unsafe.Pointer(ret)}
+}
+
+// GetProperty 获取对象 obj 的 name 属性的值，用完后需要调用 Free 方法。
+func GetProperty(obj unsafe.Pointer, name string) Value {
+	v := NewPropertyValue(obj, name)
+	if v.P == nil {
+		return v
+	}
+	cName := (*C.gchar)(CString(name))
+	C.g_object_get_property((*C.GObject)(obj), cName, v.native())
+	Free(unsafe.Pointer(cName))
+	return v
+}
+
+// SetProperty 把对象 obj 的 name 属性设置为 v。
+func SetProperty(obj unsafe.Pointer, name string, v Value) {
+	if v.P == nil {
+		return
+	}
+	cName := (*C.gchar)(CString(name))
+	C.g_object_set_property((*C.GObject)(obj), cName, v.native())
+	Free(unsafe.Pointer(cName))
+}
+
+func (v *Value) Free() {
+	if v.P == nil {
+		return
+	}
+	C.free_value(v.native())
+	v.P = nil
+}
+
+func (v Value) Bool() bool {
+	if v.P == nil {
+		return false
+	}
+	return C.g_value_get_boolean(v.native()) != 0
+}
+
+func (v Value) SetBool(val bool) {
+	if v.P == nil {
+		return
+	}
+	C.g_value_set_boolean(v.native(), C.gboolean(Bool2Int(val)))
+}
+
+// Int64 获取整数、enum 或 flags 类型的值
+func (v Value) Int64() int64 {
+	if v.P == nil {
+		return 0
+	}
+	return int64(C.value_get_int64(v.native()))
+}
+
+func (v Value) SetInt64(val int64) {
+	if v.P == nil {
+		return
+	}
+	C.value_set_int64(v.native(), C.gint64(val))
+}
+
+func (v Value) Uint64() uint64 {
+	return uint64(v.Int64())
+}
+
+func (v Value) SetUint64(val uint64) {
+	v.SetInt64(int64(val))
+}
+
+// Double 获取 float 或 double 类型的值
+func (v Value) Double() float64 {
+	if v.P == nil {
+		return 0
+	}
+	return float64(C.value_get_double(v.native()))
+}
+
+func (v Value) SetDouble(val float64) {
+	if v.P == nil {
+		return
+	}
+	C.value_set_double(v.native(), C.gdouble(val))
+}
+
+func (v Value) String() string {
+	if v.P == nil {
+		return ""
+	}
+	return GoString(unsafe.Pointer(C.g_value_get_string(v.native())))
+}
+
+func (v Value) SetString(val string) {
+	if v.P == nil {
+		return
+	}
+	cVal := (*C.gchar)(CString(val))
+	C.g_value_set_string(v.native(), cVal)
+	Free(unsafe.Pointer(cVal))
+}
+
+func (v Value) GType() GType {
+	if v.P == nil {
+		return 0
+	}
+	return GType(C.g_value_get_gtype(v.native()))
+}
+
+func (v Value) SetGType(val GType) {
+	if v.P == nil {
+		return
+	}
+	C.g_value_set_gtype(v.native(), C.GType(val))
+}
+
+// Pointer 获取 object、boxed、pointer、param 或 variant 类型的值，
+// 对于 object 类型，不增加引用计数；对于 boxed 和 variant 类型，返回的是副本。
+func (v Value) Pointer() unsafe.Pointer {
+	if v.P == nil {
+		return nil
+	}
+	return unsafe.Pointer(C.value_get_pointer(v.native(), 1))
+}
+
+// PeekPointer 和 Pointer 类似，但是对于 boxed 和 variant 类型，返回的不是副本，
+// 只在 GValue 有效期间可用，比如在信号的处理函数中。
+func (v Value) PeekPointer() unsafe.Pointer {
+	if v.P == nil {
+		return nil
+	}
+	return unsafe.Pointer(C.value_get_pointer(v.native(), 0))
+}
+
+func (v Value) SetPointer(val unsafe.Pointer) {
+	if v.P == nil {
+		return
+	}
+	C.value_set_pointer(v.native(), C.gpointer(val))
+}
@@ -555,8 +555,10 @@ func (bi *BaseInfo) Typelib() *Typelib {
 	return &Typelib{C.g_base_info_get_typelib(bi.c)}
 }
 
-//gboolean            g_base_info_equal                   (GIBaseInfo *info1,
-//                                                         GIBaseInfo *info2);
+// g_base_info_equal
+func (bi *BaseInfo) Equal(other *BaseInfo) bool {
+	return C.g_base_info_equal(bi.c, other.c) != 0
+}
 
 //------------------------------------------------------------------------------
 // ArgInfo
@@ -635,6 +637,9 @@ func (t Transfer) String() (str string) {
 
 // g_arg_info_get_direction
 func (ai *ArgInfo) Direction() Direction {
+	if override := ai.getOverride(); override != nil && override.Direction != nil {
+		return *override.Direction
+	}
 	return Direction(C.g_arg_info_get_direction((*C.GIArgInfo)(ai.c)))
 }
 
@@ -650,11 +655,17 @@ func (ai *ArgInfo) IsReturnValue() bool {
 
 // g_arg_info_is_optional
 func (ai *ArgInfo) IsOptional() bool {
+	if override := ai.getOverride(); override != nil && override.Nullable != nil {
+		return *override.Nullable
+	}
 	return C.g_arg_info_is_optional((*C.GIArgInfo)(ai.c)) != 0
 }
 
 // g_arg_info_may_be_null
 func (ai *ArgInfo) MayBeNil() bool {
+	if override := ai.getOverride(); override != nil && override.Nullable != nil {
+		return *override.Nullable
+	}
 	return C.g_arg_info_may_be_null((*C.GIArgInfo)(ai.c)) != 0
 }
 
@@ -665,11 +676,17 @@ func (ai *ArgInfo) IsSkip() bool {
 
 // g_arg_info_get_ownership_transfer
 func (ai *ArgInfo) OwnershipTransfer() Transfer {
+	if override := ai.getOverride(); override != nil && override.Transfer != nil {
+		return *override.Transfer
+	}
 	return Transfer(C.g_arg_info_get_ownership_transfer((*C.GIArgInfo)(ai.c)))
 }
 
 // g_arg_info_get_scope
 func (ai *ArgInfo) Scope() ScopeType {
+	if override := ai.getOverride(); override != nil && override.Scope != nil {
+		return *override.Scope
+	}
 	return ScopeType(C.g_arg_info_get_scope((*C.GIArgInfo)(ai.c)))
 }
 
@@ -814,7 +831,20 @@ type PropertyInfo struct {
 	BaseInfo
 }
 
-//GParamFlags         g_property_info_get_flags           (GIPropertyInfo *info);
+type ParamFlags int
+
+const (
+	PARAM_READABLE       ParamFlags = C.G_PARAM_READABLE
+	PARAM_WRITABLE       ParamFlags = C.G_PARAM_WRITABLE
+	PARAM_CONSTRUCT      ParamFlags = C.G_PARAM_CONSTRUCT
+	PARAM_CONSTRUCT_ONLY ParamFlags = C.G_PARAM_CONSTRUCT_ONLY
+	PARAM_DEPRECATED     ParamFlags = C.G_PARAM_DEPRECATED
+)
+
+// g_property_info_get_flags
+func (pi *PropertyInfo) Flags() ParamFlags {
+	return ParamFlags(C.g_property_info_get_flags((*C.GIPropertyInfo)(pi.c)))
+}
 
 // g_property_info_get_type
 func (pi *PropertyInfo) Type() *TypeInfo {
@@ -880,11 +910,17 @@ func (tt TypeTag) String() string {
 
 // g_type_info_is_pointer
 func (ti *TypeInfo) IsPointer() bool {
+	if elemType := ti.getElemTypeOverride(); elemType != nil {
+		return elemType.IsPointer
+	}
 	return C.g_type_info_is_pointer((*C.GITypeInfo)(ti.c)) != 0
 }
 
 // g_type_info_get_tag
 func (ti *TypeInfo) Tag() TypeTag {
+	if elemType := ti.getElemTypeOverride(); elemType != nil {
+		return elemType.Tag
+	}
 	return TypeTag(C.g_type_info_get_tag((*C.GITypeInfo)(ti.c)))
 }
 
@@ -896,6 +932,16 @@ func (ti *TypeInfo) ParamType(n int) *TypeInfo {
 
 // g_type_info_get_interface
 func (ti *TypeInfo) Interface() *BaseInfo {
+	if elemType := ti.getElemTypeOverride(); elemType != nil {
+		if elemType.Tag != TYPE_TAG_INTERFACE {
+			return nil
+		}
+		bi := defaultRepo.FindByName(elemType.Namespace, elemType.Name)
+		if bi.IsNil() {
+			return nil
+		}
+		return bi
+	}
 	cptr := C.g_type_info_get_interface((*C.GITypeInfo)(ti.c))
 	if cptr == nil {
 		return nil
@@ -906,6 +952,9 @@ func (ti *TypeInfo) Interface() *BaseInfo {
 
 // g_type_info_get_array_length
 func (ti *TypeInfo) ArrayLength() int {
+	if override := ti.getOverride(); override != nil && override.ArrayLength != nil {
+		return *override.ArrayLength
+	}
 	return int(C.g_type_info_get_array_length((*C.GITypeInfo)(ti.c)))
 }
 
@@ -940,11 +989,17 @@ func (ci *CallableInfo) ReturnType() *TypeInfo {
 
 // g_callable_info_get_caller_owns
 func (ci *CallableInfo) CallerOwns() Transfer {
+	if override := ci.getReturnOverride(); override != nil && override.Transfer != nil {
+		return *override.Transfer
+	}
 	return Transfer(C.g_callable_info_get_caller_owns((*C.GICallableInfo)(ci.c)))
 }
 
 // g_callable_info_may_return_null
 func (ci *CallableInfo) MayReturnNil() bool {
+	if override := ci.getReturnOverride(); override != nil && override.Nullable != nil {
+		return *override.Nullable
+	}
 	return C.g_callable_info_may_return_null((*C.GICallableInfo)(ci.c)) != 0
 }
 
@@ -966,6 +1021,11 @@ func (ci *CallableInfo) IterateReturnAttributes(cb func(name, value string)) {
 	}
 }
 
+// g_callable_info_can_throw_gerror
+func (ci *CallableInfo) CanThrowGError() bool {
+	return C.g_callable_info_can_throw_gerror((*C.GICallableInfo)(ci.c)) != 0
+}
+
 // g_callable_info_get_n_args
 func (ci *CallableInfo) NumArg() int {
 	return int(C.g_callable_info_get_n_args((*C.GICallableInfo)(ci.c)))
@@ -1241,6 +1301,12 @@ func (ei *EnumInfo) StorageType() TypeTag {
 	return TypeTag(C.g_enum_info_get_storage_type((*C.GIEnumInfo)(ei.c)))
 }
 
+// g_enum_info_get_error_domain
+// 返回错误域的名字，比如 "g-io-error-quark"，如果此枚举不是错误域的错误代码，则返回空字符串。
+func (ei *EnumInfo) ErrorDomain() string {
+	return _GStringToGoString(C.g_enum_info_get_error_domain((*C.GIEnumInfo)(ei.c)))
+}
+
 // g_value_info_get_value
 func (vi *ValueInfo) Value() int64 {
 	return int64(C.g_value_info_get_value((*C.GIValueInfo)(vi.c)))
@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2019 ~ 2020 Uniontech Software Technology Co.,Ltd
+ *
+ * Author:
+ *
+ * Maintainer:
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Override 是对参数或返回值的注解的修正，用于纠正 GIR 文件中错误的注解，字段为 nil 表示不修正。
+type Override struct {
+	Direction   *Direction
+	Transfer    *Transfer
+	Nullable    *bool
+	ArrayLength *int      // 数组长度参数的索引
+	ElemType    *ElemType // 数组和列表的元素类型
+	KeyType     *ElemType // GHashTable 的键的类型
+	ValueType   *ElemType // GHashTable 的值的类型
+	Scope       *ScopeType
+}
+
+// ElemType 是数组、列表等容器类型的元素类型。
+type ElemType struct {
+	Tag       TypeTag
+	IsPointer bool
+	Namespace string // Tag 为 TYPE_TAG_INTERFACE 时有效
+	Name      string // Tag 为 TYPE_TAG_INTERFACE 时有效
+}
+
+var _basicElemTypes = map[string]TypeTag{
+	"gpointer": TYPE_TAG_VOID,
+	"gboolean": TYPE_TAG_BOOLEAN,
+	"gint8":    TYPE_TAG_INT8,
+	"guint8":   TYPE_TAG_UINT8,
+	"gint16":   TYPE_TAG_INT16,
+	"guint16":  TYPE_TAG_UINT16,
+	"gint":     TYPE_TAG_INT32,
+	"gint32":   TYPE_TAG_INT32,
+	"guint":    TYPE_TAG_UINT32,
+	"guint32":  TYPE_TAG_UINT32,
+	"gint64":   TYPE_TAG_INT64,
+	"guint64":  TYPE_TAG_UINT64,
+	"gfloat":   TYPE_TAG_FLOAT,
+	"gdouble":  TYPE_TAG_DOUBLE,
+	"GType":    TYPE_TAG_GTYPE,
+	"utf8":     TYPE_TAG_UTF8,
+	"filename": TYPE_TAG_FILENAME,
+	"gunichar": TYPE_TAG_UNICHAR,
+}
+
+// ParseElemType 解析元素类型，str 可以是基本类型名，比如 utf8, gint；
+// 也可以是 "命名空间.类型名"，比如 Gio.File，以 * 结尾表示元素是指针，比如 Gio.File*。
+func ParseElemType(str string) (*ElemType, error) {
+	name := strings.TrimSuffix(str, "*")
+	isPointer := name != str
+	if tag, ok := _basicElemTypes[name]; ok {
+		switch tag {
+		case TYPE_TAG_VOID, TYPE_TAG_UTF8, TYPE_TAG_FILENAME:
+			isPointer = true
+		}
+		return &ElemType{Tag: tag, IsPointer: isPointer}, nil
+	}
+
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid element type %q", str)
+	}
+	return &ElemType{
+		Tag:       TYPE_TAG_INTERFACE,
+		IsPointer: isPointer,
+		Namespace: parts[0],
+		Name:      parts[1],
+	}, nil
+}
+
+// OverrideFunc 返回 callable 的名为 argName 的参数的修正，argName 为 "return" 表示返回值，
+// 返回 nil 表示不修正。
+type OverrideFunc func(callable *CallableInfo, argName string) *Override
+
+var _overrideFunc OverrideFunc
+
+// SetOverrideFunc 设置修正函数，之后 ArgInfo, TypeInfo 和 CallableInfo 的相关方法会返回修正后的结果。
+func SetOverrideFunc(fn OverrideFunc) {
+	_overrideFunc = fn
+}
+
+func (ai *ArgInfo) getOverride() *Override {
+	if _overrideFunc == nil {
+		return nil
+	}
+	container := ai.Container()
+	if container == nil {
+		return nil
+	}
+	return _overrideFunc(ToCallableInfo(container), ai.Name())
+}
+
+func (ci *CallableInfo) getReturnOverride() *Override {
+	if _overrideFunc == nil {
+		return nil
+	}
+	return _overrideFunc(ci, "return")
+}
+
+// 获取参数或返回值的类型的修正
+func (ti *TypeInfo) getOverride() *Override {
+	if _overrideFunc == nil {
+		return nil
+	}
+	container := ti.Container()
+	if container == nil {
+		return nil
+	}
+	switch container.Type() {
+	case INFO_TYPE_ARG:
+		return ToArgInfo(container).getOverride()
+	case INFO_TYPE_FUNCTION, INFO_TYPE_CALLBACK, INFO_TYPE_SIGNAL, INFO_TYPE_VFUNC:
+		// 返回值的类型
+		return ToCallableInfo(container).getReturnOverride()
+	}
+	return nil
+}
+
+// 如果 ti 是参数或返回值的类型的元素类型，获取元素类型的修正，GHashTable 的键和值分别修正。
+func (ti *TypeInfo) getElemTypeOverride() *ElemType {
+	if _overrideFunc == nil {
+		return nil
+	}
+	container := ti.Container()
+	if container == nil || container.Type() != INFO_TYPE_TYPE {
+		return nil
+	}
+	parent := ToTypeInfo(container)
+	override := parent.getOverride()
+	if override == nil {
+		return nil
+	}
+	if parent.Tag() == TYPE_TAG_GHASH {
+		keyTi := parent.ParamType(0)
+		isKey := ti.Equal(&keyTi.BaseInfo)
+		keyTi.Unref()
+		if isKey {
+			return override.KeyType
+		}
+		return override.ValueType
+	}
+	return override.ElemType
+}
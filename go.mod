@@ -0,0 +1,9 @@
+module github.com/electricface/go-gir3
+
+go 1.13
+
+require (
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da
+	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c
+)
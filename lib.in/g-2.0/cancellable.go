@@ -0,0 +1,41 @@
+package g
+
+import (
+	"context"
+	"sync"
+)
+
+// CancellableFromContext 创建一个 Cancellable，当 ctx 结束（被取消或超时）时，它会被取消。
+// 用完之后必须调用返回的 release 函数，它会停止监视 ctx，并释放 Cancellable。
+// 如果 ctx 永远不会结束，即 ctx.Done() 返回 nil，则返回的 Cancellable 的 P 为 nil，
+// 作为参数传递给 GIO 函数时相当于 NULL。
+func CancellableFromContext(ctx context.Context) (cancellable Cancellable, release func()) {
+	done := ctx.Done()
+	if done == nil {
+		return Cancellable{}, func() {}
+	}
+
+	cancellable = NewCancellable()
+	stop := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-done:
+			// g_cancellable_cancel 是线程安全的
+			cancellable.Cancel()
+		case <-stop:
+		}
+	}()
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			close(stop)
+			// 等待监视的 goroutine 退出后再释放，避免取消一个已经被释放的对象。
+			<-exited
+			cancellable.Unref()
+		})
+	}
+	return
+}
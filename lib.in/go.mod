@@ -0,0 +1,5 @@
+// lib.in 中是手写的文件，girgen 把它们复制到生成的模块中编译，
+// 这个 go.mod 使它们不属于 girgen 所在的模块。
+module github.com/electricface/go-gir
+
+go 1.13